@@ -0,0 +1,132 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import "strings"
+
+// Dialect name constants recognized by BaseBuilder.
+const (
+	DialectMySQL    = "mysql"
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+// Builder is implemented by *DB and *Tx. It is the indirection point that
+// lets SQL generation vary per dialect without SelectQuery (or any
+// Expression) needing to know which database it is talking to.
+type Builder interface {
+	// DialectName identifies the active SQL dialect (DialectMySQL,
+	// DialectPostgres, DialectSQLite, ...).
+	DialectName() string
+
+	// QuoteTableName quotes a (possibly schema-qualified) table name
+	// using the active dialect's identifier quoting rules.
+	QuoteTableName(name string) string
+
+	// QuoteColumnName quotes a (possibly table-qualified) column name
+	// using the active dialect's identifier quoting rules.
+	QuoteColumnName(name string) string
+
+	// QuoteLockingClause renders the trailing locking clause for a
+	// SELECT ... FOR UPDATE/FOR SHARE query. It returns ErrLockNotSupported
+	// if the dialect cannot express the requested lock.
+	QuoteLockingClause(forUpdate bool, wait lockWait, of []string) (string, error)
+
+	// Select starts a new SelectQuery against this DB/Tx.
+	Select(cols ...interface{}) *SelectQuery
+
+	// NewQuery prepares a raw SQL statement (as used by generated dbxgen
+	// code) for binding and execution against this DB/Tx.
+	NewQuery(sql string) *Query
+
+	// executor returns the underlying database/sql handle used to run
+	// statements built by this Builder.
+	executor() sqlExecutor
+
+	// policyRegistry returns the policy registry consulted by SelectQuery
+	// when rendering row-level policy filters. It is never nil.
+	policyRegistry() *policyRegistry
+}
+
+// BaseBuilder implements Builder's SQL-rendering concerns for a single
+// dialect, selected by the dialect field. *DB and *Tx embed BaseBuilder and
+// add the executor/policies/Select/NewQuery methods that need access to the
+// surrounding connection.
+type BaseBuilder struct {
+	dialect string
+}
+
+// DialectName implements Builder.
+func (b *BaseBuilder) DialectName() string {
+	return b.dialect
+}
+
+// QuoteTableName implements Builder.
+func (b *BaseBuilder) QuoteTableName(name string) string {
+	return quoteIdentifier(b.dialect, name)
+}
+
+// QuoteColumnName implements Builder.
+func (b *BaseBuilder) QuoteColumnName(name string) string {
+	return quoteIdentifier(b.dialect, name)
+}
+
+// QuoteLockingClause implements Builder. MySQL and Postgres share the same
+// `FOR UPDATE|SHARE [OF table...] [NOWAIT|SKIP LOCKED]` syntax; SQLite has
+// no row-locking model (it serializes writers at the database level) so any
+// lock request there is an error rather than a silent no-op, matching the
+// behavior of ForUpdate/ForShare on an engine that cannot honor them.
+func (b *BaseBuilder) QuoteLockingClause(forUpdate bool, wait lockWait, of []string) (string, error) {
+	if b.dialect == DialectSQLite {
+		return "", ErrLockNotSupported
+	}
+
+	clause := "FOR SHARE"
+	if forUpdate {
+		clause = "FOR UPDATE"
+	}
+	if len(of) > 0 {
+		names := make([]string, len(of))
+		for i, t := range of {
+			names[i] = b.QuoteTableName(t)
+		}
+		clause += " OF " + strings.Join(names, ", ")
+	}
+	switch wait {
+	case lockWaitNoWait:
+		clause += " NOWAIT"
+	case lockWaitSkipLocked:
+		clause += " SKIP LOCKED"
+	}
+	return clause, nil
+}
+
+func quoteIdentifier(dialect, name string) string {
+	parts := strings.Split(name, ".")
+	quote := "`"
+	if dialect == DialectPostgres {
+		quote = `"`
+	}
+	for i, p := range parts {
+		parts[i] = quote + p + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+// quoteOrderByColumn quotes the column portion of an ORDER BY item while
+// leaving a trailing ASC/DESC direction keyword outside the quoting, e.g.
+// "age DESC" -> "`age` DESC" but "id" -> "`id`". Both SelectQuery.OrderBy
+// and Window.OrderBy share this so a window's ORDER BY renders consistently
+// with the outer query's.
+func quoteOrderByColumn(builder Builder, col string) string {
+	col = strings.TrimSpace(col)
+	if i := strings.LastIndexByte(col, ' '); i >= 0 {
+		name, dir := col[:i], strings.ToUpper(strings.TrimSpace(col[i+1:]))
+		if dir == "ASC" || dir == "DESC" {
+			return builder.QuoteColumnName(strings.TrimSpace(name)) + " " + dir
+		}
+	}
+	return builder.QuoteColumnName(col)
+}
@@ -0,0 +1,198 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"text/template"
+)
+
+// model is a generated result struct, either declared explicitly via a
+// `-- name: X :model` block or inferred from a query's column list.
+type model struct {
+	Name    string
+	Columns []string
+}
+
+// extractModels collects the explicit :model blocks out of queries (which,
+// per parseQueries, holds both executable queries and :model blocks
+// together - see executableQueries for the complementary filter).
+func extractModels(queries []*query) []*model {
+	seen := map[string]bool{}
+	var models []*model
+	for _, q := range queries {
+		if q.ModelName == "" {
+			continue
+		}
+		if seen[q.ModelName] {
+			continue
+		}
+		seen[q.ModelName] = true
+		models = append(models, &model{Name: q.ModelName, Columns: q.Columns})
+	}
+	return models
+}
+
+// executableQueries filters out the :model blocks that parseQueries threads
+// through queries alongside the actual executable statements - a :model
+// block only describes a result shape and has no SQL of its own, so it must
+// never reach the template's per-query method generation.
+func executableQueries(queries []*query) []*query {
+	exec := make([]*query, 0, len(queries))
+	for _, q := range queries {
+		if q.Kind == kindModel {
+			continue
+		}
+		exec = append(exec, q)
+	}
+	return exec
+}
+
+const tmplSrc = `// Code generated by dbxgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"github.com/theHamdiz/dbx"
+)
+
+// {{.Struct}} wraps a dbx.Builder (satisfied by both *dbx.DB and *dbx.Tx) with
+// the typed methods generated from the annotated SQL files in this package.
+type {{.Struct}} struct {
+	db dbx.Builder
+}
+
+// New{{.Struct}} returns a {{.Struct}} backed by db, which may be a *dbx.DB
+// or a *dbx.Tx.
+func New{{.Struct}}(db dbx.Builder) *{{.Struct}} {
+	return &{{.Struct}}{db: db}
+}
+{{range .Models}}
+// {{.Name}} is the result shape for queries returning this model.
+type {{.Name}} struct {
+{{range .Columns}}	{{toGoName .}} interface{} ` + "`db:\"{{.}}\"`" + `
+{{end}}}
+{{end}}
+{{range .Queries}}
+// {{.Name}} wraps the statement:
+//
+//	{{.SQL}}
+func (q *{{$.Struct}}) {{.Name}}({{paramList .Params}}) {{returnType .}} {
+	query := q.db.NewQuery({{printf "%q" .SQL}})
+{{range .Params}}	query.Bind(dbx.Params{"{{.Name}}": {{.Name}}})
+{{end}}{{genBody .}}}
+{{end}}
+`
+
+func generate(pkgName, structName string, queries []*query, models []*model) ([]byte, error) {
+	funcs := template.FuncMap{
+		"toGoName":   toGoName,
+		"paramList":  paramList,
+		"returnType": returnType,
+		"genBody":    genBody,
+	}
+	tmpl, err := template.New("dbxgen").Funcs(funcs).Parse(tmplSrc)
+	if err != nil {
+		return nil, err
+	}
+
+	allModels := append(append([]*model{}, models...), inferredRowModels(queries)...)
+	execQueries := executableQueries(queries)
+
+	var buf bytes.Buffer
+	data := struct {
+		Package string
+		Struct  string
+		Models  []*model
+		Queries []*query
+	}{pkgName, structName, allModels, execQueries}
+
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return buf.Bytes(), fmt.Errorf("gofmt: %w (unformatted source returned)", err)
+	}
+	return formatted, nil
+}
+
+func toGoName(s string) string {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '_' })
+	for i, p := range parts {
+		if p == "" {
+			continue
+		}
+		parts[i] = strings.ToUpper(p[:1]) + p[1:]
+	}
+	return strings.Join(parts, "")
+}
+
+func paramList(params []param) string {
+	decls := make([]string, len(params))
+	for i, p := range params {
+		t := p.Type
+		if t == "" {
+			t = "interface{}"
+		}
+		decls[i] = p.Name + " " + t
+	}
+	return strings.Join(decls, ", ")
+}
+
+func returnType(q *query) string {
+	switch q.Kind {
+	case kindOne:
+		return "(" + resultType(q) + ", error)"
+	case kindMany:
+		return "([]" + resultType(q) + ", error)"
+	default:
+		return "error"
+	}
+}
+
+func resultType(q *query) string {
+	if q.ModelName != "" {
+		return q.ModelName
+	}
+	if len(q.Columns) == 0 {
+		return "interface{}"
+	}
+	return toGoName(q.Name) + "Row"
+}
+
+// inferredRowModels synthesizes a result struct for each :one/:many query
+// that has no explicit `:model` block but does have an inferred column
+// list, under the same <Name>Row name resultType assigns it - otherwise
+// that name would appear in generated method signatures with no struct
+// ever declaring it.
+func inferredRowModels(queries []*query) []*model {
+	var models []*model
+	for _, q := range queries {
+		if q.ModelName != "" || len(q.Columns) == 0 {
+			continue
+		}
+		if q.Kind != kindOne && q.Kind != kindMany {
+			continue
+		}
+		models = append(models, &model{Name: toGoName(q.Name) + "Row", Columns: q.Columns})
+	}
+	return models
+}
+
+func genBody(q *query) string {
+	switch q.Kind {
+	case kindOne:
+		return fmt.Sprintf("\tvar result %s\n\terr := query.One(&result)\n\treturn result, err\n", resultType(q))
+	case kindMany:
+		return fmt.Sprintf("\tvar results []%s\n\terr := query.All(&results)\n\treturn results, err\n", resultType(q))
+	default:
+		return "\t_, err := query.Execute()\n\treturn err\n"
+	}
+}
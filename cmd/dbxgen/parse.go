@@ -0,0 +1,176 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// queryKind is the annotation following a `-- name: X :kind` comment.
+type queryKind string
+
+const (
+	kindOne   queryKind = "one"
+	kindMany  queryKind = "many"
+	kindExec  queryKind = "exec"
+	kindModel queryKind = "model"
+)
+
+// validKinds is the set queryKind annotations are checked against. :batch is
+// deliberately not included: sqlc gives it distinct semantics (N param sets
+// bound and executed together, N results/errors back), and nothing in this
+// package implements that - accepting the annotation while generating a
+// plain :many method would silently promise behavior it doesn't have.
+var validKinds = map[queryKind]bool{
+	kindOne:   true,
+	kindMany:  true,
+	kindExec:  true,
+	kindModel: true,
+}
+
+// param describes one `/* @param name type */` annotation attached to a
+// query.
+type param struct {
+	Name string
+	Type string
+}
+
+// query is a single parsed, annotated SQL statement.
+type query struct {
+	Name      string
+	Kind      queryKind
+	SQL       string
+	Params    []param
+	ModelName string   // set when the query is a :model block
+	Columns   []string // result columns, inferred from SELECT list or RETURNING
+}
+
+var (
+	nameDirective = regexp.MustCompile(`(?m)^--\s*name:\s*(\w+)\s*:(\w+)\s*$`)
+
+	// paramDirective matches the block-comment annotation style
+	// "/* @param name type */", which may appear anywhere in a block.
+	paramDirective = regexp.MustCompile(`/\*\s*@param\s+(\w+)\s+([\w.\[\]*]+)\s*\*/`)
+
+	// paramLineDirective matches the line-comment style ("-- @param name
+	// type") used in practice by hand-written .sql files, since "--" is
+	// already the comment marker SQL tooling (and this package's own
+	// nameDirective) uses everywhere else in these files.
+	paramLineDirective = regexp.MustCompile(`(?m)^--\s*@param\s+(\w+)\s+([\w.\[\]*]+)\s*$`)
+)
+
+// parseQueries splits src into annotated blocks and parses each one.
+func parseQueries(src string) ([]*query, error) {
+	matches := nameDirective.FindAllStringSubmatchIndex(src, -1)
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	var queries []*query
+	for i, m := range matches {
+		nameStart, nameEnd := m[2], m[3]
+		kindStart, kindEnd := m[4], m[5]
+		blockStart := m[1]
+		blockEnd := len(src)
+		if i+1 < len(matches) {
+			blockEnd = matches[i+1][0]
+		}
+		block := src[blockStart:blockEnd]
+
+		q := &query{
+			Name: src[nameStart:nameEnd],
+			Kind: queryKind(src[kindStart:kindEnd]),
+		}
+		if !validKinds[q.Kind] {
+			return nil, fmt.Errorf("query %q: unsupported kind %q", q.Name, q.Kind)
+		}
+
+		type paramMatch struct {
+			start int
+			p     param
+		}
+		var paramMatches []paramMatch
+		for _, m := range paramDirective.FindAllStringSubmatchIndex(block, -1) {
+			paramMatches = append(paramMatches, paramMatch{m[0], param{Name: block[m[2]:m[3]], Type: block[m[4]:m[5]]}})
+		}
+		for _, m := range paramLineDirective.FindAllStringSubmatchIndex(block, -1) {
+			paramMatches = append(paramMatches, paramMatch{m[0], param{Name: block[m[2]:m[3]], Type: block[m[4]:m[5]]}})
+		}
+		sort.Slice(paramMatches, func(i, j int) bool { return paramMatches[i].start < paramMatches[j].start })
+		for _, m := range paramMatches {
+			q.Params = append(q.Params, m.p)
+		}
+
+		stmt := paramDirective.ReplaceAllString(block, "")
+		stmt = strings.TrimSpace(stripLineComments(stmt))
+		stmt = strings.TrimSuffix(stmt, ";")
+
+		if q.Kind == kindModel {
+			q.ModelName = q.Name
+			q.Columns = inferColumns(stmt)
+			// :model blocks only describe a result shape; they don't
+			// execute anything on their own, but they still need to come
+			// back out of parseQueries so extractModels can find them.
+			queries = append(queries, q)
+			continue
+		}
+
+		if stmt == "" {
+			return nil, fmt.Errorf("query %q has no SQL statement", q.Name)
+		}
+		q.SQL = stmt
+		q.Columns = inferColumns(stmt)
+		queries = append(queries, q)
+	}
+	return queries, nil
+}
+
+func stripLineComments(s string) string {
+	lines := strings.Split(s, "\n")
+	kept := lines[:0]
+	for _, l := range lines {
+		if strings.HasPrefix(strings.TrimSpace(l), "--") {
+			continue
+		}
+		kept = append(kept, l)
+	}
+	return strings.Join(kept, "\n")
+}
+
+var (
+	selectList  = regexp.MustCompile(`(?is)^SELECT\s+(.*?)\s+FROM\s`)
+	returningCl = regexp.MustCompile(`(?is)RETURNING\s+(.*?)\s*$`)
+)
+
+// inferColumns makes a best-effort guess at the result column list of a
+// statement, either from its SELECT list or a trailing RETURNING clause.
+// Wildcard selects ("SELECT *") yield no columns; the generated method then
+// returns interface{} (scanned via One/All) instead of a row struct.
+func inferColumns(stmt string) []string {
+	var list string
+	if m := selectList.FindStringSubmatch(stmt); m != nil {
+		list = m[1]
+	} else if m := returningCl.FindStringSubmatch(stmt); m != nil {
+		list = m[1]
+	} else {
+		return nil
+	}
+	if strings.TrimSpace(list) == "*" {
+		return nil
+	}
+	parts := strings.Split(list, ",")
+	cols := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if i := strings.LastIndexAny(p, " ."); i >= 0 {
+			p = p[i+1:]
+		}
+		cols = append(cols, p)
+	}
+	return cols
+}
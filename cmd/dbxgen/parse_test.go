@@ -0,0 +1,100 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleSQL = `
+-- name: GetUserByID :one
+-- @param id int64
+SELECT id, name, email FROM users WHERE id = {:id};
+
+-- name: ListUsers :many
+SELECT id, name, email FROM users ORDER BY id;
+
+-- name: DeleteUser :exec
+-- @param id int64
+DELETE FROM users WHERE id = {:id};
+`
+
+func TestParseQueries(t *testing.T) {
+	queries, err := parseQueries(sampleSQL)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(queries))
+
+	assert.Equal(t, "GetUserByID", queries[0].Name)
+	assert.Equal(t, kindOne, queries[0].Kind)
+	assert.Equal(t, []param{{Name: "id", Type: "int64"}}, queries[0].Params)
+	assert.Equal(t, []string{"id", "name", "email"}, queries[0].Columns)
+
+	assert.Equal(t, kindMany, queries[1].Kind)
+	assert.Equal(t, kindExec, queries[2].Kind)
+}
+
+const modelSQL = `
+-- name: User :model
+SELECT id, name, email FROM users;
+
+-- name: GetUserByID :one
+-- @param id int64
+SELECT * FROM users WHERE id = {:id};
+`
+
+// TestParseQueries_ModelBlock verifies that a :model block comes back out of
+// parseQueries (it has no SQL of its own to execute, but extractModels still
+// needs it in order to find it) alongside the queries that actually run.
+func TestParseQueries_ModelBlock(t *testing.T) {
+	queries, err := parseQueries(modelSQL)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(queries))
+
+	assert.Equal(t, "User", queries[0].Name)
+	assert.Equal(t, kindModel, queries[0].Kind)
+	assert.Equal(t, "User", queries[0].ModelName)
+	assert.Equal(t, []string{"id", "name", "email"}, queries[0].Columns)
+	assert.Equal(t, "", queries[0].SQL)
+
+	assert.Equal(t, "GetUserByID", queries[1].Name)
+	assert.Equal(t, kindOne, queries[1].Kind)
+
+	models := extractModels(queries)
+	if assert.Equal(t, 1, len(models)) {
+		assert.Equal(t, "User", models[0].Name)
+		assert.Equal(t, []string{"id", "name", "email"}, models[0].Columns)
+	}
+
+	exec := executableQueries(queries)
+	if assert.Equal(t, 1, len(exec)) {
+		assert.Equal(t, "GetUserByID", exec[0].Name)
+	}
+}
+
+// TestParseQueries_UnsupportedKind verifies that :batch - a kind sqlc gives
+// real batched-execution semantics to, which this package doesn't implement
+// - is rejected with a clear error rather than silently generating a :many
+// lookalike.
+func TestParseQueries_UnsupportedKind(t *testing.T) {
+	_, err := parseQueries(`
+-- name: BatchGetUsers :batch
+-- @param id int64
+SELECT id, name, email FROM users WHERE id = {:id};
+`)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "BatchGetUsers")
+	assert.Contains(t, err.Error(), "batch")
+}
+
+func TestInferColumns_Wildcard(t *testing.T) {
+	assert.Nil(t, inferColumns("SELECT * FROM users"))
+}
+
+func TestInferColumns_Returning(t *testing.T) {
+	cols := inferColumns("INSERT INTO users (name) VALUES ({:name}) RETURNING id, name")
+	assert.Equal(t, []string{"id", "name"}, cols)
+}
@@ -0,0 +1,72 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import (
+	"strings"
+)
+
+// cteDef represents a single common table expression registered via With or
+// WithRecursive.
+type cteDef struct {
+	name      string
+	columns   []string
+	query     *Query
+	recursive bool
+}
+
+// With prepends a named common table expression to the query, equivalent to
+// `WITH name(columns...) AS (q)`. Multiple calls accumulate; they are
+// rendered in the order they were registered.
+func (q *SelectQuery) With(name string, sub *Query, columns ...string) *SelectQuery {
+	q.ctes = append(q.ctes, cteDef{name: name, columns: columns, query: sub})
+	return q
+}
+
+// WithRecursive behaves like With but marks the overall WITH clause as
+// `WITH RECURSIVE`. A single recursive CTE is enough to upgrade the whole
+// clause, matching MySQL 8 and Postgres semantics.
+func (q *SelectQuery) WithRecursive(name string, sub *Query, columns ...string) *SelectQuery {
+	q.ctes = append(q.ctes, cteDef{name: name, columns: columns, query: sub, recursive: true})
+	return q
+}
+
+// buildCTEClause renders the WITH clause (if any CTEs were registered). Each
+// CTE's params are merged into existing via renameParams, so a name already
+// bound by the main query, a union leg, or a sibling CTE is renamed rather
+// than silently overwritten - existing must therefore hold every param
+// accumulated so far, not a fresh map scoped to the CTEs alone. Quoting of
+// the CTE name and its column list goes through builder so dialects stay in
+// control of identifier syntax.
+func buildCTEClause(builder Builder, ctes []cteDef, existing Params) string {
+	if len(ctes) == 0 {
+		return ""
+	}
+
+	recursive := false
+	parts := make([]string, len(ctes))
+	for i, c := range ctes {
+		if c.recursive {
+			recursive = true
+		}
+		def := builder.QuoteTableName(c.name)
+		if len(c.columns) > 0 {
+			cols := make([]string, len(c.columns))
+			for j, col := range c.columns {
+				cols[j] = builder.QuoteColumnName(col)
+			}
+			def += "(" + strings.Join(cols, ", ") + ")"
+		}
+		sqlText := renameParams(c.query.SQL(), c.query.Params(), existing)
+		parts[i] = def + " AS (" + sqlText + ")"
+	}
+
+	clause := "WITH "
+	if recursive {
+		clause += "RECURSIVE "
+	}
+	clause += strings.Join(parts, ", ")
+	return clause
+}
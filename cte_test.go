@@ -0,0 +1,77 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectQuery_With(t *testing.T) {
+	db := getDB()
+
+	sub := db.Select().From("users").Where(NewExp("active=1")).Build()
+	q := db.Select().With("active_users", sub, "id", "name").From("active_users").Build()
+
+	expected := "WITH `active_users`(`id`, `name`) AS (SELECT * FROM `users` WHERE active=1) SELECT * FROM `active_users`"
+	assert.Equal(t, expected, q.SQL())
+}
+
+func TestSelectQuery_WithRecursive(t *testing.T) {
+	db := getDB()
+
+	base := db.Select().From("categories").Where(NewExp("parent_id IS NULL")).Build()
+	q := db.Select().WithRecursive("tree", base).From("tree").Build()
+
+	assert.Equal(t, "WITH RECURSIVE `tree` AS (SELECT * FROM `categories` WHERE parent_id IS NULL) SELECT * FROM `tree`", q.SQL())
+}
+
+func TestSelectQuery_With_ParamCollision(t *testing.T) {
+	db := getDB()
+
+	a := db.Select().From("users").Where(NewExp("status={:status}", Params{"status": 1})).Build()
+	b := db.Select().From("archived_users").Where(NewExp("status={:status}", Params{"status": 2})).Build()
+	q := db.Select().With("active", a).With("archived", b).From("active").Build()
+
+	assert.Equal(t, 2, len(q.Params()), "both CTEs' params should survive, renamed to avoid collision")
+
+	var statuses []interface{}
+	for k, v := range q.Params() {
+		assert.Contains(t, k, "status")
+		statuses = append(statuses, v)
+	}
+	assert.ElementsMatch(t, []interface{}{1, 2}, statuses)
+}
+
+func TestSelectQuery_With_ParamCollisionWithOuterQuery(t *testing.T) {
+	db := getDB()
+
+	sub := db.Select().From("users").Where(NewExp("status={:status}", Params{"status": 1})).Build()
+	q := db.Select().With("active", sub).From("active").
+		Where(NewExp("status={:status}", Params{"status": 99})).
+		Build()
+
+	assert.Equal(t, 2, len(q.Params()), "the CTE's status param and the outer query's status param must both survive")
+
+	var statuses []interface{}
+	for k, v := range q.Params() {
+		assert.Contains(t, k, "status")
+		statuses = append(statuses, v)
+	}
+	assert.ElementsMatch(t, []interface{}{1, 99}, statuses)
+}
+
+func TestSelectQuery_With_Union(t *testing.T) {
+	db := getDB()
+
+	sub := db.Select().From("users").Build()
+	q1 := db.Select().From("a").Build()
+	q2 := db.Select().From("b").Build()
+	q := db.Select().With("u", sub).From("a").Union(q1).UnionAll(q2).Build()
+
+	expected := "WITH `u` AS (SELECT * FROM `users`) (SELECT * FROM `a`) UNION (SELECT * FROM `a`) UNION ALL (SELECT * FROM `b`)"
+	assert.Equal(t, expected, q.SQL())
+}
@@ -0,0 +1,60 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import "database/sql"
+
+// DB wraps a *sql.DB with dialect-aware query building. Create one with Open
+// or NewFromDB.
+type DB struct {
+	*sql.DB
+	BaseBuilder
+	builder  Builder
+	policies *policyRegistry
+}
+
+// Open opens a database connection via database/sql and wraps it in a DB
+// using dialect, one of DialectMySQL, DialectPostgres, or DialectSQLite.
+func Open(driverName, dataSourceName, dialect string) (*DB, error) {
+	sqlDB, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromDB(sqlDB, dialect), nil
+}
+
+// NewFromDB wraps an already-open *sql.DB in a DB using dialect, one of
+// DialectMySQL, DialectPostgres, or DialectSQLite.
+func NewFromDB(sqlDB *sql.DB, dialect string) *DB {
+	db := &DB{DB: sqlDB, BaseBuilder: BaseBuilder{dialect: dialect}, policies: newPolicyRegistry()}
+	db.builder = db
+	return db
+}
+
+func (db *DB) executor() sqlExecutor { return db.DB }
+
+func (db *DB) policyRegistry() *policyRegistry { return db.policies }
+
+// Select starts a new SelectQuery against this DB.
+func (db *DB) Select(cols ...interface{}) *SelectQuery {
+	return newSelectQuery(db.builder).Select(cols...)
+}
+
+// NewQuery prepares a raw SQL statement (as used by generated dbxgen code)
+// for binding and execution against this DB.
+func (db *DB) NewQuery(sqlText string) *Query {
+	return newQuery(db.DB, sqlText, nil)
+}
+
+// Begin starts a transaction sharing this DB's dialect and policies.
+func (db *DB) Begin() (*Tx, error) {
+	sqlTx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	tx := &Tx{Tx: sqlTx, BaseBuilder: db.BaseBuilder, policies: db.policies}
+	tx.builder = tx
+	return tx, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import (
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Customer/CustomerPtr/User are the struct fixtures shared by the test
+// suite. CustomerPtr mirrors Customer but with a pointer field, exercising
+// the pointer-scanning path; tableNameOf trims its "Ptr" suffix so both map
+// to the "customer" table.
+type Customer struct {
+	ID    int64  `db:"pk"`
+	Email string `db:""`
+}
+
+type CustomerPtr struct {
+	ID    int64   `db:"pk"`
+	Email *string `db:""`
+}
+
+type User struct {
+	ID int64 `db:"pk"`
+}
+
+// compositeRow exercises Model's composite-primary-key path (pk.go).
+type compositeRow struct {
+	ID1 string `db:"pk"`
+	ID2 string `db:"pk"`
+}
+
+// getDB returns a DB that renders MySQL-flavored SQL (backtick quoting,
+// FOR UPDATE/FOR SHARE support) but is never actually executed against; it
+// backs the many tests that only call Build()/Validate().
+func getDB() *DB {
+	return NewFromDB(nil, DialectMySQL)
+}
+
+// getPostgresDB is getDB's Postgres-flavored counterpart.
+func getPostgresDB() *DB {
+	return NewFromDB(nil, DialectPostgres)
+}
+
+// getSQLiteDB is getDB's SQLite-flavored counterpart, used to exercise the
+// features (locking, REGEXP) that SQLite can't express.
+func getSQLiteDB() *DB {
+	return NewFromDB(nil, DialectSQLite)
+}
+
+// getPreparedDB returns a DB backed by a real, freshly seeded in-memory
+// SQLite database, declared with dialect MySQL so generated SQL keeps using
+// backtick quoting while still executing against a real engine.
+var preparedDBSeq int64
+
+func getPreparedDB() *DB {
+	// database/sql pools connections, and each new physical connection to a
+	// bare ":memory:" DSN gets its own blank database; a named shared-cache
+	// DSN lets every pooled connection see the same in-memory database
+	// instead. The name is unique per call so concurrent/successive tests
+	// don't share (or collide creating) the same schema.
+	name := fmt.Sprintf("file:dbx_test_%d?mode=memory&cache=shared", atomic.AddInt64(&preparedDBSeq, 1))
+	conn, err := sql.Open("sqlite3", name)
+	if err != nil {
+		panic(err)
+	}
+	db := NewFromDB(conn, DialectMySQL)
+	if _, err := db.DB.Exec(testSchema); err != nil {
+		panic(err)
+	}
+	return db
+}
+
+const testSchema = `
+CREATE TABLE customer (
+	id    INTEGER PRIMARY KEY,
+	email TEXT
+);
+INSERT INTO customer (id, email) VALUES
+	(1, 'user1@example.com'),
+	(2, 'user2@example.com'),
+	(3, 'user3@example.com');
+
+CREATE TABLE user (
+	id INTEGER PRIMARY KEY
+);
+INSERT INTO user (id) VALUES (1), (2);
+
+CREATE TABLE preloadorder (
+	id          INTEGER PRIMARY KEY,
+	customer_id INTEGER
+);
+INSERT INTO preloadorder (id, customer_id) VALUES
+	(101, 1),
+	(102, 1),
+	(201, 2);
+
+CREATE TABLE preloadpost (
+	id    INTEGER PRIMARY KEY,
+	title TEXT
+);
+INSERT INTO preloadpost (id, title) VALUES
+	(1, 'Hello dbx'),
+	(2, 'CTEs and windows');
+
+CREATE TABLE preloadtag (
+	id   INTEGER PRIMARY KEY,
+	name TEXT
+);
+INSERT INTO preloadtag (id, name) VALUES
+	(1, 'sql'),
+	(2, 'go'),
+	(3, 'orm');
+
+CREATE TABLE post_tag (
+	post_id INTEGER,
+	tag_id  INTEGER
+);
+INSERT INTO post_tag (post_id, tag_id) VALUES
+	(1, 1),
+	(1, 2),
+	(2, 2),
+	(2, 3);
+
+CREATE TABLE compositerow (
+	id1 TEXT,
+	id2 TEXT
+);
+INSERT INTO compositerow (id1, id2) VALUES ('a', 'b');
+`
@@ -0,0 +1,21 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import "errors"
+
+// MissingPKError is returned by Model (and the equivalent Update/Delete
+// model helpers) when the destination struct has no field tagged `db:"pk"`.
+var MissingPKError = errors.New("dbx: no primary key is specified for the model")
+
+// VarTypeError is returned when a value passed to the package does not have
+// the expected type (e.g. Model's dest argument must be a pointer to a
+// struct).
+type VarTypeError string
+
+// Error implements the error interface.
+func (e VarTypeError) Error() string {
+	return string(e)
+}
@@ -0,0 +1,150 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// rawExp is a literal SQL fragment, optionally carrying its own bound
+// parameters, as produced by NewExp.
+type rawExp struct {
+	exp    string
+	params Params
+}
+
+// NewExp creates an Expression from a raw SQL fragment. An optional Params
+// map may be supplied to bind the placeholders (e.g. "{:name}") used within
+// exp.
+func NewExp(exp string, params ...Params) Expression {
+	e := &rawExp{exp: exp}
+	if len(params) > 0 {
+		e.params = params[0]
+	}
+	return e
+}
+
+// Build implements Expression.
+func (e *rawExp) Build(builder Builder, params Params) string {
+	for k, v := range e.params {
+		params[k] = v
+	}
+	return e.exp
+}
+
+// HashExp is a set of column-value pairs, each rendered as an equality
+// condition and ANDed together. Keys are rendered in sorted order so the
+// generated SQL (and therefore any test asserting on it) is deterministic
+// regardless of Go's randomized map iteration.
+type HashExp map[string]interface{}
+
+// Build implements Expression.
+func (e HashExp) Build(builder Builder, params Params) string {
+	if len(e) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		name := paramName(params, k)
+		params[name] = e[k]
+		parts[i] = fmt.Sprintf("%s={:%s}", builder.QuoteColumnName(k), name)
+	}
+	return joinAnded(parts)
+}
+
+// andExp ANDs together the render of each of its sub-expressions, wrapping
+// each one in parentheses. orExp does the same with OR. Together they back
+// SelectQuery.AndWhere/OrWhere (and the Having equivalents), which nest
+// these as each clause is added so that precedence matches the order
+// conditions were attached in.
+type andExp struct{ exps []Expression }
+type orExp struct{ exps []Expression }
+
+// And combines exps with AND, wrapping each one in parentheses.
+func And(exps ...Expression) Expression {
+	return andExp{exps}
+}
+
+// Or combines exps with OR, wrapping each one in parentheses.
+func Or(exps ...Expression) Expression {
+	return orExp{exps}
+}
+
+func (e andExp) Build(builder Builder, params Params) string {
+	return buildBoolExp(e.exps, builder, params, " AND ")
+}
+
+func (e orExp) Build(builder Builder, params Params) string {
+	return buildBoolExp(e.exps, builder, params, " OR ")
+}
+
+func buildBoolExp(exps []Expression, builder Builder, params Params, glue string) string {
+	parts := make([]string, 0, len(exps))
+	for _, e := range exps {
+		if e == nil {
+			continue
+		}
+		if s := e.Build(builder, params); s != "" {
+			parts = append(parts, "("+s+")")
+		}
+	}
+	return strings.Join(parts, glue)
+}
+
+func joinAnded(parts []string) string {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	for i := range parts {
+		parts[i] = "(" + parts[i] + ")"
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// inExp renders `column IN (?, ?, ...)`.
+type inExp struct {
+	col    string
+	values []interface{}
+}
+
+// In builds an Expression testing whether col's value is one of values.
+func In(col string, values ...interface{}) Expression {
+	return &inExp{col: col, values: values}
+}
+
+// Build implements Expression.
+func (e *inExp) Build(builder Builder, params Params) string {
+	if len(e.values) == 0 {
+		return "0=1"
+	}
+	placeholders := make([]string, len(e.values))
+	for i, v := range e.values {
+		name := paramName(params, e.col)
+		params[name] = v
+		placeholders[i] = "{:" + name + "}"
+	}
+	return builder.QuoteColumnName(e.col) + " IN (" + strings.Join(placeholders, ", ") + ")"
+}
+
+// paramName returns a parameter placeholder name derived from col that
+// doesn't collide with any name already present in params.
+func paramName(params Params, col string) string {
+	base := strings.ReplaceAll(col, ".", "_")
+	name := base
+	for i := 1; ; i++ {
+		if _, ok := params[name]; !ok {
+			return name
+		}
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// dialectValidator is implemented by Expression types that can only render
+// meaningful SQL on some dialects (e.g. Regexp on SQLite). SelectQuery calls
+// Validate whenever such an expression is attached via Where/AndWhere/
+// OrWhere/Having/AndHaving/OrHaving/joins, surfacing the problem through
+// Build().Error() rather than only as text embedded in the generated SQL.
+type dialectValidator interface {
+	Validate(builder Builder) error
+}
+
+// Like is a HashExp-style expression set that renders each key/value pair as
+// a case-sensitive `column LIKE ?` condition, ANDed together.
+type Like map[string]interface{}
+
+// ILike behaves like Like but is case-insensitive: on Postgres it renders
+// `column ILIKE ?`; on MySQL (whose default collation is already
+// case-insensitive for most charsets, but not for binary/utf8mb4_bin
+// columns) it renders `LOWER(column) LIKE ?` with a lowercased argument.
+type ILike map[string]interface{}
+
+// NotLike is the negated form of Like.
+type NotLike map[string]interface{}
+
+// Regexp renders each key/value pair as `column REGEXP ?` (MySQL) or
+// `column ~ ?` (Postgres); it is not supported on SQLite.
+type Regexp map[string]interface{}
+
+// IRegexp is the case-insensitive counterpart of Regexp, rendering
+// `LOWER(column) REGEXP ?` with a lower-cased argument on MySQL, or
+// `column ~* ?` on Postgres. It is not supported on SQLite.
+type IRegexp map[string]interface{}
+
+// Build implements Expression for Like.
+func (e Like) Build(builder Builder, params Params) string {
+	return buildPatternExp(builder, params, map[string]interface{}(e), "LIKE", false)
+}
+
+// Build implements Expression for ILike.
+func (e ILike) Build(builder Builder, params Params) string {
+	return buildPatternExp(builder, params, map[string]interface{}(e), "ILIKE", true)
+}
+
+// Build implements Expression for NotLike.
+func (e NotLike) Build(builder Builder, params Params) string {
+	return buildPatternExp(builder, params, map[string]interface{}(e), "NOT LIKE", false)
+}
+
+// Build implements Expression for Regexp.
+func (e Regexp) Build(builder Builder, params Params) string {
+	return buildRegexpExp(builder, params, map[string]interface{}(e), false)
+}
+
+// Build implements Expression for IRegexp.
+func (e IRegexp) Build(builder Builder, params Params) string {
+	return buildRegexpExp(builder, params, map[string]interface{}(e), true)
+}
+
+// Validate implements dialectValidator for Regexp.
+func (e Regexp) Validate(builder Builder) error {
+	return validateRegexpDialect(builder)
+}
+
+// Validate implements dialectValidator for IRegexp.
+func (e IRegexp) Validate(builder Builder) error {
+	return validateRegexpDialect(builder)
+}
+
+func validateRegexpDialect(builder Builder) error {
+	switch builder.DialectName() {
+	case DialectMySQL, DialectPostgres:
+		return nil
+	default:
+		return fmt.Errorf("dbx: REGEXP is not supported by the %s dialect", builder.DialectName())
+	}
+}
+
+func buildPatternExp(builder Builder, params Params, m map[string]interface{}, op string, ci bool) string {
+	if len(m) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(m))
+	for col, val := range m {
+		name := paramName(params, col)
+		value := val
+		column := builder.QuoteColumnName(col)
+		switch builder.DialectName() {
+		case DialectPostgres:
+			// ILIKE is native; nothing special to do.
+		case DialectMySQL:
+			if ci {
+				if s, ok := value.(string); ok {
+					value = strings.ToLower(s)
+				}
+				column = "LOWER(" + column + ")"
+				op = "LIKE"
+			}
+		}
+		params[name] = value
+		parts = append(parts, fmt.Sprintf("%s %s {:%s}", column, op, name))
+	}
+	return strings.Join(parts, " AND ")
+}
+
+func buildRegexpExp(builder Builder, params Params, m map[string]interface{}, ci bool) string {
+	if len(m) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(m))
+	for col, val := range m {
+		column := builder.QuoteColumnName(col)
+		switch builder.DialectName() {
+		case DialectPostgres:
+			op := "~"
+			if ci {
+				op = "~*"
+			}
+			name := paramName(params, col)
+			params[name] = val
+			parts = append(parts, fmt.Sprintf("%s %s {:%s}", column, op, name))
+		case DialectMySQL:
+			value := val
+			if ci {
+				if s, ok := val.(string); ok {
+					value = strings.ToLower(s)
+				}
+				column = "LOWER(" + column + ")"
+			}
+			name := paramName(params, col)
+			params[name] = value
+			parts = append(parts, fmt.Sprintf("%s REGEXP {:%s}", column, name))
+		default:
+			parts = append(parts, "dbx: REGEXP is not supported by the "+builder.DialectName()+" dialect")
+		}
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// JSONContains builds an expression testing whether the JSON document in
+// col contains val at path (a MySQL-style JSON path such as "$.tags" or
+// "$.a.b"). It renders `JSON_CONTAINS(col, ?, path)` on MySQL and
+// `col #> path @> ?::jsonb` on Postgres, translating path into Postgres's
+// `{key,...}` text-array path syntax and JSON-encoding val for the jsonb
+// cast. It is not supported on SQLite.
+func JSONContains(col, path string, val interface{}) Expression {
+	return &jsonContainsExp{col: col, path: path, val: val}
+}
+
+type jsonContainsExp struct {
+	col, path string
+	val       interface{}
+}
+
+func (e *jsonContainsExp) Build(builder Builder, params Params) string {
+	name := paramName(params, e.col)
+	switch builder.DialectName() {
+	case DialectPostgres:
+		data, err := json.Marshal(e.val)
+		if err != nil {
+			data = []byte("null")
+		}
+		params[name] = string(data)
+		return fmt.Sprintf("%s #> '%s' @> {:%s}::jsonb", builder.QuoteColumnName(e.col), mysqlPathToPostgresArray(e.path), name)
+	case DialectMySQL:
+		params[name] = e.val
+		return fmt.Sprintf("JSON_CONTAINS(%s, {:%s}, '%s')", builder.QuoteColumnName(e.col), name, e.path)
+	default:
+		return "dbx: JSONContains is not supported by the " + builder.DialectName() + " dialect"
+	}
+}
+
+// Validate implements dialectValidator for JSONContains.
+func (e *jsonContainsExp) Validate(builder Builder) error {
+	switch builder.DialectName() {
+	case DialectMySQL, DialectPostgres:
+		return nil
+	default:
+		return fmt.Errorf("dbx: JSONContains is not supported by the %s dialect", builder.DialectName())
+	}
+}
+
+// mysqlPathToPostgresArray converts a MySQL-style JSON path ("$.tags",
+// "$.a.b") into the text-array path syntax Postgres's #> operator expects
+// ("{tags}", "{a,b}").
+func mysqlPathToPostgresArray(path string) string {
+	p := strings.TrimPrefix(path, "$.")
+	p = strings.TrimPrefix(p, "$")
+	if p == "" {
+		return "{}"
+	}
+	return "{" + strings.Join(strings.Split(p, "."), ",") + "}"
+}
+
+// ArrayContains builds an expression testing whether col (an array/set
+// column) contains val. It renders `val = ANY(col)` on Postgres and
+// `FIND_IN_SET(?, col)` on MySQL. It is not supported on SQLite.
+func ArrayContains(col string, val interface{}) Expression {
+	return &arrayContainsExp{col: col, val: val}
+}
+
+type arrayContainsExp struct {
+	col string
+	val interface{}
+}
+
+func (e *arrayContainsExp) Build(builder Builder, params Params) string {
+	name := paramName(params, e.col)
+	params[name] = e.val
+	switch builder.DialectName() {
+	case DialectPostgres:
+		return fmt.Sprintf("{:%s} = ANY(%s)", name, builder.QuoteColumnName(e.col))
+	case DialectMySQL:
+		return fmt.Sprintf("FIND_IN_SET({:%s}, %s)", name, builder.QuoteColumnName(e.col))
+	default:
+		return "dbx: ArrayContains is not supported by the " + builder.DialectName() + " dialect"
+	}
+}
+
+// Validate implements dialectValidator for ArrayContains.
+func (e *arrayContainsExp) Validate(builder Builder) error {
+	switch builder.DialectName() {
+	case DialectMySQL, DialectPostgres:
+		return nil
+	default:
+		return fmt.Errorf("dbx: ArrayContains is not supported by the %s dialect", builder.DialectName())
+	}
+}
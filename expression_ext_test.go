@@ -0,0 +1,56 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLike(t *testing.T) {
+	db := getDB()
+	params := Params{}
+	sql := Like{"name": "jo%"}.Build(db.builder, params)
+	assert.Equal(t, "`name` LIKE {:name}", sql)
+	assert.Equal(t, "jo%", params["name"])
+}
+
+func TestILike_MySQL_LowercasesArg(t *testing.T) {
+	db := getDB()
+	params := Params{}
+	sql := ILike{"name": "Jo%"}.Build(db.builder, params)
+	assert.Equal(t, "LOWER(`name`) LIKE {:name}", sql)
+	assert.Equal(t, "jo%", params["name"])
+}
+
+func TestRegexp_Postgres(t *testing.T) {
+	db := getPostgresDB()
+	params := Params{}
+	sql := Regexp{"email": "@example\\.com$"}.Build(db.builder, params)
+	assert.Equal(t, `"email" ~ {:email}`, sql)
+}
+
+func TestRegexp_SQLite_NotSupported(t *testing.T) {
+	db := getSQLiteDB()
+	params := Params{}
+	sql := Regexp{"email": "x"}.Build(db.builder, params)
+	assert.Contains(t, sql, "not supported")
+}
+
+func TestJSONContains(t *testing.T) {
+	db := getDB()
+	params := Params{}
+	sql := JSONContains("meta", "$.tags", "vip").Build(db.builder, params)
+	assert.Equal(t, "JSON_CONTAINS(`meta`, {:meta}, '$.tags')", sql)
+	assert.Equal(t, "vip", params["meta"])
+}
+
+func TestArrayContains(t *testing.T) {
+	db := getDB()
+	params := Params{}
+	sql := ArrayContains("tags", "vip").Build(db.builder, params)
+	assert.Equal(t, "FIND_IN_SET({:tags}, `tags`)", sql)
+}
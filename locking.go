@@ -0,0 +1,80 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import "errors"
+
+// lockMode identifies the kind of row lock requested on a SelectQuery.
+type lockMode int
+
+const (
+	lockNone lockMode = iota
+	lockForUpdate
+	lockForShare
+)
+
+// lockWait controls NOWAIT/SKIP LOCKED behavior on engines that support it.
+type lockWait int
+
+const (
+	lockWaitBlock lockWait = iota
+	lockWaitNoWait
+	lockWaitSkipLocked
+)
+
+// ErrLockNotSupported is recorded by Build (and reported by Query.Error and
+// on execution) when the active dialect cannot express a requested
+// locking clause, or when the lock is requested on a query that also uses UNION/UNION ALL.
+var ErrLockNotSupported = errors.New("dbx: locking clause is not supported here")
+
+// ForUpdate marks the query as SELECT ... FOR UPDATE.
+func (q *SelectQuery) ForUpdate() *SelectQuery {
+	q.lockMode = lockForUpdate
+	return q
+}
+
+// ForShare marks the query as SELECT ... FOR SHARE. The clause is always
+// rendered as FOR SHARE; the older MySQL LOCK IN SHARE MODE form is not
+// emitted.
+func (q *SelectQuery) ForShare() *SelectQuery {
+	q.lockMode = lockForShare
+	return q
+}
+
+// ForUpdateOf marks the query as SELECT ... FOR UPDATE OF the given tables.
+// The OF list is rendered on both MySQL and Postgres; SQLite rejects any
+// lock with ErrLockNotSupported.
+func (q *SelectQuery) ForUpdateOf(tables ...string) *SelectQuery {
+	q.lockMode = lockForUpdate
+	q.lockOfTables = tables
+	return q
+}
+
+// NoWait requests that the lock fail immediately instead of blocking if rows
+// are already locked.
+func (q *SelectQuery) NoWait() *SelectQuery {
+	q.lockWait = lockWaitNoWait
+	return q
+}
+
+// SkipLocked requests that already-locked rows be silently excluded instead
+// of blocking.
+func (q *SelectQuery) SkipLocked() *SelectQuery {
+	q.lockWait = lockWaitSkipLocked
+	return q
+}
+
+// buildLockClause asks builder to render the lock clause for mode/wait/of,
+// returning an error if the dialect cannot express it or if hasUnion is set
+// (locking clauses are not legal inside a UNION leg on most engines).
+func buildLockClause(builder Builder, mode lockMode, wait lockWait, of []string, hasUnion bool) (string, error) {
+	if mode == lockNone {
+		return "", nil
+	}
+	if hasUnion {
+		return "", ErrLockNotSupported
+	}
+	return builder.QuoteLockingClause(mode == lockForUpdate, wait, of)
+}
@@ -0,0 +1,47 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectQuery_ForUpdate(t *testing.T) {
+	db := getDB()
+
+	q := db.Select().From("users").Where(NewExp("id=1")).ForUpdate().Build()
+	assert.Equal(t, "SELECT * FROM `users` WHERE id=1 FOR UPDATE", q.SQL())
+}
+
+func TestSelectQuery_ForShare_SkipLocked(t *testing.T) {
+	db := getDB()
+
+	q := db.Select().From("users").ForShare().SkipLocked().Build()
+	assert.Equal(t, "SELECT * FROM `users` FOR SHARE SKIP LOCKED", q.SQL())
+}
+
+func TestSelectQuery_ForUpdateOf_NoWait(t *testing.T) {
+	db := getDB()
+
+	q := db.Select().From("users").InnerJoin("profile", nil).ForUpdateOf("users").NoWait().Build()
+	assert.Equal(t, "SELECT * FROM `users` INNER JOIN `profile` FOR UPDATE OF `users` NOWAIT", q.SQL())
+}
+
+func TestSelectQuery_ForUpdate_Union_Error(t *testing.T) {
+	db := getDB()
+
+	other := db.Select().From("archived_users").Build()
+	q := db.Select().From("users").ForUpdate().Union(other).Build()
+	assert.Equal(t, ErrLockNotSupported, q.Error())
+}
+
+func TestSelectQuery_ForUpdate_SQLite_NotSupported(t *testing.T) {
+	db := getSQLiteDB()
+
+	q := db.Select().From("users").ForUpdate().Build()
+	assert.Equal(t, ErrLockNotSupported, q.Error())
+}
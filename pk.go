@@ -0,0 +1,89 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// CompositePK wraps the key values for a struct with more than one `db:"pk"`
+// field, in the order the pk tags appear on the struct. Passing a
+// CompositePK to Model/Update/Delete is equivalent to passing a plain slice
+// of the same values; it exists mainly for readability at call sites.
+type CompositePK struct {
+	Values []interface{}
+}
+
+// NewCompositePK is a convenience constructor for CompositePK.
+func NewCompositePK(values ...interface{}) CompositePK {
+	return CompositePK{Values: values}
+}
+
+// pkValues normalizes the pk argument accepted by Model/Update/Delete model
+// helpers into an ordered slice of key values, matching the order the pk
+// fields were declared on structType. pk may be a single value (for a
+// single-column PK), a []interface{}/CompositePK (positional composite key),
+// or a struct whose fields (matched by name) supply the composite key.
+func pkValues(pk interface{}, structType reflect.Type, pkFields []reflect.StructField) ([]interface{}, error) {
+	switch v := pk.(type) {
+	case CompositePK:
+		return checkPKCount(v.Values, pkFields)
+	case []interface{}:
+		return checkPKCount(v, pkFields)
+	}
+
+	rv := reflect.ValueOf(pk)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Struct && rv.Type() != reflect.TypeOf(CompositePK{}) {
+		values := make([]interface{}, len(pkFields))
+		for i, f := range pkFields {
+			fv := rv.FieldByName(f.Name)
+			if !fv.IsValid() {
+				return nil, fmt.Errorf("dbx: pk struct is missing field %q", f.Name)
+			}
+			values[i] = fv.Interface()
+		}
+		return values, nil
+	}
+
+	return checkPKCount([]interface{}{pk}, pkFields)
+}
+
+func checkPKCount(values []interface{}, pkFields []reflect.StructField) ([]interface{}, error) {
+	if len(values) != len(pkFields) {
+		return nil, fmt.Errorf("dbx: expected %d primary key value(s), got %d", len(pkFields), len(values))
+	}
+	return values, nil
+}
+
+// pkEqExp renders a single `column={:param}` equality condition for one
+// field of a (possibly composite) primary key.
+type pkEqExp struct {
+	col string
+	val interface{}
+}
+
+// Build implements Expression.
+func (e *pkEqExp) Build(builder Builder, params Params) string {
+	name := paramName(params, e.col)
+	params[name] = e.val
+	return fmt.Sprintf("%s={:%s}", builder.QuoteColumnName(e.col), name)
+}
+
+// pkWhere builds the WHERE expression used by Model, Update, and Delete
+// model helpers, ANDing one equality condition per pk field in the order the
+// pk tags were declared on the struct. Unlike a HashExp (which renders its
+// keys in sorted order), this guarantees the rendered WHERE clause
+// reproduces that declaration order every time.
+func pkWhere(pkFields []reflect.StructField, values []interface{}) Expression {
+	exps := make([]Expression, len(pkFields))
+	for i, f := range pkFields {
+		exps[i] = &pkEqExp{col: columnName(f), val: values[i]}
+	}
+	return And(exps...)
+}
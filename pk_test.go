@@ -0,0 +1,38 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPkValues(t *testing.T) {
+	type key struct {
+		ID1 string `db:"pk"`
+		ID2 string `db:"pk"`
+	}
+	fields := []reflect.StructField{
+		{Name: "ID1", Tag: `db:"pk"`},
+		{Name: "ID2", Tag: `db:"pk"`},
+	}
+
+	values, err := pkValues([]interface{}{"a", "b"}, reflect.TypeOf(key{}), fields)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", "b"}, values)
+
+	values, err = pkValues(CompositePK{Values: []interface{}{"a", "b"}}, reflect.TypeOf(key{}), fields)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", "b"}, values)
+
+	values, err = pkValues(key{ID1: "a", ID2: "b"}, reflect.TypeOf(key{}), fields)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", "b"}, values)
+
+	_, err = pkValues([]interface{}{"a"}, reflect.TypeOf(key{}), fields)
+	assert.Error(t, err)
+}
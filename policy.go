@@ -0,0 +1,92 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import "sync"
+
+// PolicyContext carries the information a policy needs to decide what a
+// caller is allowed to see. Identity is typically a user or service ID;
+// Claims holds arbitrary additional attributes (roles, tenant ID, etc.).
+type PolicyContext struct {
+	Identity string
+	Claims   map[string]interface{}
+}
+
+// Policy builds the Expression that gets ANDed into the WHERE (or, for
+// joined tables, the ON) clause whenever its table is referenced in a
+// SelectQuery.
+type Policy func(ctx PolicyContext) Expression
+
+// policyRegistry holds the policies registered against a DB, keyed by table
+// name. A table may have more than one policy; all of them AND together.
+type policyRegistry struct {
+	mu       sync.RWMutex
+	policies map[string][]Policy
+}
+
+func newPolicyRegistry() *policyRegistry {
+	return &policyRegistry{policies: map[string][]Policy{}}
+}
+
+// AddPolicy registers policy against table. Every SelectQuery built from
+// this DB (unless BypassPolicies was called) will AND policy's expression
+// into the WHERE clause whenever table appears in FROM, and into the ON
+// clause whenever table appears as a join target.
+func (db *DB) AddPolicy(table string, policy Policy) {
+	db.policies.mu.Lock()
+	defer db.policies.mu.Unlock()
+	db.policies.policies[table] = append(db.policies.policies[table], policy)
+}
+
+func (r *policyRegistry) forTable(table string) []Policy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.policies[table]
+}
+
+// WithPolicyContext attaches the PolicyContext that policies will be
+// evaluated with for this query. It must be called before Build/One/All for
+// policies to take effect; without it, an empty PolicyContext is used.
+func (q *SelectQuery) WithPolicyContext(ctx PolicyContext) *SelectQuery {
+	q.policyContext = &ctx
+	return q
+}
+
+// BypassPolicies disables policy evaluation entirely for this query. It is
+// intended for admin tooling and background jobs that must see every row.
+func (q *SelectQuery) BypassPolicies() *SelectQuery {
+	q.bypassPolicies = true
+	return q
+}
+
+// policyExpr ANDs together every policy registered for table, evaluated
+// against the query's PolicyContext. It returns nil if there are no
+// applicable policies or policies are bypassed.
+func (q *SelectQuery) policyExpr(table string) Expression {
+	if q.bypassPolicies || q.builder == nil {
+		return nil
+	}
+	policies := q.policyRegistry.forTable(table)
+	if len(policies) == 0 {
+		return nil
+	}
+	ctx := PolicyContext{}
+	if q.policyContext != nil {
+		ctx = *q.policyContext
+	}
+	exprs := make([]Expression, 0, len(policies))
+	for _, p := range policies {
+		if e := p(ctx); e != nil {
+			exprs = append(exprs, e)
+		}
+	}
+	if len(exprs) == 0 {
+		return nil
+	}
+	if len(exprs) == 1 {
+		return exprs[0]
+	}
+	return And(exprs...)
+}
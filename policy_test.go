@@ -0,0 +1,54 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDB_AddPolicy(t *testing.T) {
+	db := getDB()
+
+	db.AddPolicy("users", func(ctx PolicyContext) Expression {
+		return NewExp("tenant_id={:tenant}", Params{"tenant": ctx.Claims["tenant"]})
+	})
+	db.AddPolicy("users", func(ctx PolicyContext) Expression {
+		return NewExp("deleted_at IS NULL")
+	})
+
+	q := db.Select().From("users").
+		WithPolicyContext(PolicyContext{Identity: "u1", Claims: map[string]interface{}{"tenant": 5}}).
+		Build()
+
+	expected := "SELECT * FROM `users` WHERE (tenant_id={:tenant}) AND (deleted_at IS NULL)"
+	assert.Equal(t, expected, q.SQL())
+	assert.Equal(t, 5, q.Params()["tenant"])
+}
+
+func TestSelectQuery_BypassPolicies(t *testing.T) {
+	db := getDB()
+	db.AddPolicy("users", func(ctx PolicyContext) Expression {
+		return NewExp("tenant_id=1")
+	})
+
+	q := db.Select().From("users").BypassPolicies().Build()
+	assert.Equal(t, "SELECT * FROM `users`", q.SQL())
+}
+
+func TestSelectQuery_Policy_Join(t *testing.T) {
+	db := getDB()
+	db.AddPolicy("profile", func(ctx PolicyContext) Expression {
+		return NewExp("profile.active=1")
+	})
+
+	q := db.Select().From("users").
+		LeftJoin("profile", NewExp("user.id=profile.id")).
+		Build()
+
+	expected := "SELECT * FROM `users` LEFT JOIN `profile` ON (user.id=profile.id) AND (profile.active=1)"
+	assert.Equal(t, expected, q.SQL())
+}
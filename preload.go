@@ -0,0 +1,384 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// PreloadOption configures how a single Preload is executed.
+type PreloadOption func(*preloadSpec)
+
+// preloadSpec describes one relation to be eagerly loaded, resolved from the
+// `db:"rel=...` struct tag together with any PreloadOptions supplied by the
+// caller.
+type preloadSpec struct {
+	path       string
+	foreignKey string
+	localKey   string
+	through    string
+	customize  func(q *SelectQuery) *SelectQuery
+}
+
+// WithForeignKey overrides the foreign key column inferred from the
+// relation's struct tag.
+func WithForeignKey(fk string) PreloadOption {
+	return func(s *preloadSpec) {
+		s.foreignKey = fk
+	}
+}
+
+// WithLocalKey overrides the local key column inferred from the relation's
+// struct tag.
+func WithLocalKey(lk string) PreloadOption {
+	return func(s *preloadSpec) {
+		s.localKey = lk
+	}
+}
+
+// WithThrough sets the join table used for a many-to-many relation.
+func WithThrough(table string) PreloadOption {
+	return func(s *preloadSpec) {
+		s.through = table
+	}
+}
+
+// Customize registers a callback that is applied to the SelectQuery issued
+// for this relation, allowing the caller to add extra WHERE/ORDER BY/LIMIT
+// clauses to the preload query.
+func Customize(f func(q *SelectQuery) *SelectQuery) PreloadOption {
+	return func(s *preloadSpec) {
+		s.customize = f
+	}
+}
+
+// Preload marks a relation (e.g. "Orders" or the nested path "Orders.Items")
+// to be eagerly loaded the next time One, All, or Model is called on this
+// SelectQuery. The destination struct field backing the relation must carry
+// a `db:"rel=..."` tag describing at least foreignKey and, for many-to-many
+// relations, through (plus throughKey, which defaults to the singularized
+// related table name + "_id").
+func (q *SelectQuery) Preload(relation string, opts ...PreloadOption) *SelectQuery {
+	spec := &preloadSpec{path: relation}
+	for _, opt := range opts {
+		opt(spec)
+	}
+	q.preloads = append(q.preloads, spec)
+	return q
+}
+
+// Preloads is a convenience wrapper for registering several relations at
+// once, none of which take PreloadOptions.
+func (q *SelectQuery) Preloads(relations ...string) *SelectQuery {
+	for _, r := range relations {
+		q.Preload(r)
+	}
+	return q
+}
+
+// runPreloads is invoked after the primary result set has been scanned into
+// dest. dest must be the same pointer (to a struct or a slice of structs)
+// that was passed to One or All.
+func (q *SelectQuery) runPreloads(dest interface{}) error {
+	if len(q.preloads) == 0 {
+		return nil
+	}
+	for _, spec := range q.preloads {
+		if err := q.runPreload(dest, spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runPreload loads a single (possibly nested) relation into dest.
+func (q *SelectQuery) runPreload(dest interface{}, spec *preloadSpec) error {
+	parents, _ := parentStructs(dest)
+	if len(parents) == 0 {
+		return nil
+	}
+
+	head, rest := splitPreloadPath(spec.path)
+
+	field, rel, err := relationField(parents[0].Type(), head)
+	if err != nil {
+		return fmt.Errorf("dbx: Preload(%q): %w", spec.path, err)
+	}
+	if spec.foreignKey != "" {
+		rel.foreignKey = spec.foreignKey
+	}
+	if spec.localKey != "" {
+		rel.localKey = spec.localKey
+	}
+	if spec.through != "" {
+		rel.through = spec.through
+	}
+
+	localKeys := make([]interface{}, 0, len(parents))
+	seen := map[interface{}]bool{}
+	for _, p := range parents {
+		v := fieldByTag(p, rel.localKey).Interface()
+		if !seen[v] {
+			seen[v] = true
+			localKeys = append(localKeys, v)
+		}
+	}
+	if len(localKeys) == 0 {
+		return nil
+	}
+
+	childElemType := field.Type
+	if childElemType.Kind() == reflect.Slice {
+		childElemType = childElemType.Elem()
+	}
+	if childElemType.Kind() == reflect.Ptr {
+		childElemType = childElemType.Elem()
+	}
+
+	// collectType is always a slice, even for a to-one relation (field.Type a
+	// bare struct or *struct), since the child query may return more than
+	// one candidate row before the first match is picked below.
+	collectType := field.Type
+	if collectType.Kind() != reflect.Slice {
+		collectType = reflect.SliceOf(field.Type)
+	}
+	childSlicePtr := reflect.New(collectType)
+	var byParent map[interface{}][]reflect.Value
+
+	if rel.through == "" {
+		childQuery := q.builder.Select().From(rel.table).AndWhere(In(rel.foreignKey, localKeys...))
+		q.copyHooksTo(childQuery)
+		if spec.customize != nil {
+			childQuery = spec.customize(childQuery)
+		}
+		if err := childQuery.All(childSlicePtr.Interface()); err != nil {
+			return err
+		}
+		if rest != "" {
+			if err := childQuery.runPreload(childSlicePtr.Interface(), &preloadSpec{path: rest}); err != nil {
+				return err
+			}
+		}
+		byParent = map[interface{}][]reflect.Value{}
+		children := childSlicePtr.Elem()
+		for i := 0; i < children.Len(); i++ {
+			c := children.Index(i)
+			k := fieldByTag(c, rel.foreignKey).Interface()
+			byParent[k] = append(byParent[k], c)
+		}
+	} else {
+		pairs, err := q.loadThroughPairs(rel, localKeys)
+		if err != nil {
+			return err
+		}
+		childKeys := make([]interface{}, 0, len(pairs))
+		seenChild := map[interface{}]bool{}
+		for _, p := range pairs {
+			if !seenChild[p.childKey] {
+				seenChild[p.childKey] = true
+				childKeys = append(childKeys, p.childKey)
+			}
+		}
+
+		childPK := primaryKeyColumn(childElemType)
+		childQuery := q.builder.Select().From(rel.table).AndWhere(In(childPK, childKeys...))
+		q.copyHooksTo(childQuery)
+		if spec.customize != nil {
+			childQuery = spec.customize(childQuery)
+		}
+		if err := childQuery.All(childSlicePtr.Interface()); err != nil {
+			return err
+		}
+		if rest != "" {
+			if err := childQuery.runPreload(childSlicePtr.Interface(), &preloadSpec{path: rest}); err != nil {
+				return err
+			}
+		}
+
+		byChildKey := map[interface{}]reflect.Value{}
+		children := childSlicePtr.Elem()
+		for i := 0; i < children.Len(); i++ {
+			c := children.Index(i)
+			byChildKey[fieldByTag(c, childPK).Interface()] = c
+		}
+
+		byParent = map[interface{}][]reflect.Value{}
+		for _, p := range pairs {
+			if c, ok := byChildKey[p.childKey]; ok {
+				byParent[p.parentKey] = append(byParent[p.parentKey], c)
+			}
+		}
+	}
+
+	for _, p := range parents {
+		k := fieldByTag(p, rel.localKey).Interface()
+		matches := byParent[k]
+		if rel.many {
+			slice := reflect.MakeSlice(field.Type, 0, len(matches))
+			for _, m := range matches {
+				slice = reflect.Append(slice, m)
+			}
+			fieldValue(p, head).Set(slice)
+		} else if len(matches) > 0 {
+			fv := fieldValue(p, head)
+			if fv.Kind() == reflect.Ptr {
+				ptr := reflect.New(fv.Type().Elem())
+				ptr.Elem().Set(matches[0])
+				fv.Set(ptr)
+			} else {
+				fv.Set(matches[0])
+			}
+		}
+	}
+
+	return nil
+}
+
+// throughPair is one (parentKey, childKey) row read from a many-to-many
+// relation's join table.
+type throughPair struct {
+	parentKey interface{}
+	childKey  interface{}
+}
+
+// loadThroughPairs queries rel.through for the (foreignKey, throughKey)
+// pairs linking localKeys to their related rows.
+func (q *SelectQuery) loadThroughPairs(rel *relation, localKeys []interface{}) ([]throughPair, error) {
+	pivotQuery := q.builder.Select(rel.foreignKey, rel.throughKey).
+		From(rel.through).
+		AndWhere(In(rel.foreignKey, localKeys...))
+	q.copyHooksTo(pivotQuery)
+	rows, err := pivotQuery.Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pairs []throughPair
+	for rows.Next() {
+		var parentKey, childKey interface{}
+		if err := rows.Scan(&parentKey, &childKey); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, throughPair{parentKey: parentKey, childKey: childKey})
+	}
+	return pairs, rows.Err()
+}
+
+func splitPreloadPath(path string) (head, rest string) {
+	if i := strings.IndexByte(path, '.'); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+	return path, ""
+}
+
+// relation holds the parsed contents of a `db:"rel=..."` struct tag.
+type relation struct {
+	table      string
+	foreignKey string
+	localKey   string
+	through    string
+	throughKey string
+	many       bool
+}
+
+// relationField locates the exported struct field named name and parses its
+// relation tag, which takes the form
+// `db:"rel=foreignKey:col,localKey:col[,through:table,throughKey:col,table:name]"`.
+// through/throughKey describe a many-to-many relation joined via a pivot
+// table: foreignKey is the column on through referencing the parent's
+// localKey, and throughKey is the column on through referencing the related
+// row's primary key (it defaults to the singularized related table name
+// plus "_id" when omitted). table overrides the related table name inferred
+// from the field's element type.
+func relationField(t reflect.Type, name string) (reflect.StructField, *relation, error) {
+	field, ok := t.FieldByName(name)
+	if !ok {
+		return field, nil, fmt.Errorf("no field named %q", name)
+	}
+	tag := field.Tag.Get("db")
+	if !strings.HasPrefix(tag, "rel=") {
+		return field, nil, fmt.Errorf("field %q has no rel= tag", name)
+	}
+
+	rel := &relation{localKey: "id"}
+	for _, kv := range strings.Split(strings.TrimPrefix(tag, "rel="), ",") {
+		parts := strings.SplitN(kv, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch parts[0] {
+		case "foreignKey":
+			rel.foreignKey = parts[1]
+		case "localKey":
+			rel.localKey = parts[1]
+		case "through":
+			rel.through = parts[1]
+		case "throughKey":
+			rel.throughKey = parts[1]
+		case "table":
+			rel.table = parts[1]
+		}
+	}
+	if rel.foreignKey == "" {
+		return field, nil, fmt.Errorf("field %q is missing a rel foreignKey", name)
+	}
+
+	ft := field.Type
+	if ft.Kind() == reflect.Slice {
+		rel.many = true
+		ft = ft.Elem()
+	}
+	if ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+	if rel.table == "" {
+		rel.table = tableNameOf(ft)
+	}
+	if rel.through != "" && rel.throughKey == "" {
+		rel.throughKey = singularize(rel.table) + "_id"
+	}
+	return field, rel, nil
+}
+
+// parentStructs normalizes dest (a *struct or *[]struct/[]*struct) into a
+// flat slice of addressable struct values.
+func parentStructs(dest interface{}) ([]reflect.Value, bool) {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr {
+		return nil, false
+	}
+	v = v.Elem()
+	if v.Kind() == reflect.Slice {
+		items := make([]reflect.Value, v.Len())
+		for i := range items {
+			e := v.Index(i)
+			if e.Kind() == reflect.Ptr {
+				e = e.Elem()
+			}
+			items[i] = e
+		}
+		return items, true
+	}
+	return []reflect.Value{v}, false
+}
+
+func fieldValue(v reflect.Value, name string) reflect.Value {
+	return v.FieldByName(name)
+}
+
+// fieldByTag returns the field of struct value v whose db column name (per
+// columnName) is column.
+func fieldByTag(v reflect.Value, column string) reflect.Value {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if columnName(t.Field(i)) == column {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
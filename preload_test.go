@@ -0,0 +1,129 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type preloadCustomer struct {
+	ID     int64          `db:"pk"`
+	Email  string         `db:""`
+	Orders []preloadOrder `db:"rel=foreignKey:customer_id,localKey:id"`
+}
+
+type preloadOrder struct {
+	ID         int64 `db:"pk"`
+	CustomerID int64 `db:"customer_id"`
+}
+
+type preloadPost struct {
+	ID    int64        `db:"pk"`
+	Title string       `db:""`
+	Tags  []preloadTag `db:"rel=foreignKey:post_id,through:post_tag,throughKey:tag_id,localKey:id,table:preloadtag"`
+}
+
+type preloadTag struct {
+	ID   int64  `db:"pk"`
+	Name string `db:""`
+}
+
+func TestSelectQuery_Preload(t *testing.T) {
+	db := getPreparedDB()
+	defer db.Close()
+
+	var customers []preloadCustomer
+	err := db.Select().From("customer").OrderBy("id").
+		Preload("Orders").
+		All(&customers)
+
+	assert.Nil(t, err)
+	if assert.Equal(t, 3, len(customers)) {
+		assert.Equal(t, 2, len(customers[0].Orders), "customer 1 should have 2 preloaded orders")
+		assert.Equal(t, 1, len(customers[1].Orders), "customer 2 should have 1 preloaded order")
+		assert.Equal(t, 0, len(customers[2].Orders), "customer 3 should have no orders")
+		for _, c := range customers {
+			for _, o := range c.Orders {
+				assert.Equal(t, c.ID, o.CustomerID, "order should belong to its customer")
+			}
+		}
+	}
+}
+
+func TestSelectQuery_Preloads(t *testing.T) {
+	q := getPreparedDB().Select().From("customer")
+	q.Preloads("Orders")
+	assert.Equal(t, 1, len(q.preloads))
+}
+
+// TestSelectQuery_Preload_ManyToMany exercises the `through`-joined relation
+// path: preloadPost.Tags is related to preloadtag via the post_tag pivot
+// table rather than a column on preloadtag itself.
+func TestSelectQuery_Preload_ManyToMany(t *testing.T) {
+	db := getPreparedDB()
+	defer db.Close()
+
+	var posts []preloadPost
+	err := db.Select().From("preloadpost").OrderBy("id").
+		Preload("Tags").
+		All(&posts)
+
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(posts)) {
+		assert.ElementsMatch(t, []string{"sql", "go"}, tagNames(posts[0].Tags))
+		assert.ElementsMatch(t, []string{"go", "orm"}, tagNames(posts[1].Tags))
+	}
+}
+
+// TestSelectQuery_Preload_Hooks verifies that hooks registered on the parent
+// query also fire for the queries Preload issues internally.
+func TestSelectQuery_Preload_Hooks(t *testing.T) {
+	db := getPreparedDB()
+	defer db.Close()
+
+	var execCount int
+	var customers []preloadCustomer
+	err := db.Select().From("customer").OrderBy("id").
+		WithExecHook(func(s *SelectQuery, op func() error) error {
+			execCount++
+			return op()
+		}).
+		Preload("Orders").
+		All(&customers)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, execCount, "exec hook should fire for both the parent query and the preloaded Orders query")
+}
+
+// TestSelectQuery_Preload_Hooks_ManyToMany verifies that hooks also fire for
+// the pivot-table lookup a through-relation Preload issues, not just its
+// final related-table query.
+func TestSelectQuery_Preload_Hooks_ManyToMany(t *testing.T) {
+	db := getPreparedDB()
+	defer db.Close()
+
+	var execCount int
+	var posts []preloadPost
+	err := db.Select().From("preloadpost").OrderBy("id").
+		WithExecHook(func(s *SelectQuery, op func() error) error {
+			execCount++
+			return op()
+		}).
+		Preload("Tags").
+		All(&posts)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, execCount, "exec hook should fire for the parent query, the pivot-table lookup, and the related-table query")
+}
+
+func tagNames(tags []preloadTag) []string {
+	names := make([]string, len(tags))
+	for i, tg := range tags {
+		names[i] = tg.Name
+	}
+	return names
+}
@@ -0,0 +1,248 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import (
+	"database/sql"
+	"reflect"
+	"regexp"
+)
+
+// sqlExecutor is satisfied by both *sql.DB and *sql.Tx, letting Query run
+// against either without caring which.
+type sqlExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Query represents a SQL statement together with the named parameters bound
+// to it. It is returned by SelectQuery.Build and by Builder.NewQuery (the
+// latter is primarily used by dbxgen-generated code).
+type Query struct {
+	executor  sqlExecutor
+	rawSQL    string
+	params    Params
+	lastError error
+}
+
+func newQuery(executor sqlExecutor, rawSQL string, params Params) *Query {
+	if params == nil {
+		params = Params{}
+	}
+	return &Query{executor: executor, rawSQL: rawSQL, params: params}
+}
+
+// SQL returns the raw, unbound SQL text (using {:name} placeholders) that
+// this query will execute.
+func (q *Query) SQL() string {
+	return q.rawSQL
+}
+
+// Params returns the named parameter values bound to this query.
+func (q *Query) Params() Params {
+	return q.params
+}
+
+// Error returns the error recorded while building this query (e.g. an
+// unsupported locking clause), if any, without needing to execute it.
+func (q *Query) Error() error {
+	return q.lastError
+}
+
+// Bind merges params into the query's existing parameter set, overwriting
+// any duplicate names.
+func (q *Query) Bind(params Params) *Query {
+	for k, v := range params {
+		q.params[k] = v
+	}
+	return q
+}
+
+var namedParamPattern = regexp.MustCompile(`\{:(\w+)\}`)
+
+// bind replaces every {:name} placeholder in rawSQL with a driver "?"
+// placeholder and returns the resulting statement together with the
+// ordered argument list.
+func bind(rawSQL string, params Params) (string, []interface{}) {
+	var args []interface{}
+	stmt := namedParamPattern.ReplaceAllStringFunc(rawSQL, func(m string) string {
+		name := namedParamPattern.FindStringSubmatch(m)[1]
+		args = append(args, params[name])
+		return "?"
+	})
+	return stmt, args
+}
+
+// Execute runs the query as a non-SELECT statement (INSERT/UPDATE/DELETE).
+func (q *Query) Execute() (sql.Result, error) {
+	if q.lastError != nil {
+		return nil, q.lastError
+	}
+	stmt, args := bind(q.rawSQL, q.params)
+	return q.executor.Exec(stmt, args...)
+}
+
+// Rows runs the query and returns the resulting *Rows for manual scanning.
+func (q *Query) Rows() (*Rows, error) {
+	if q.lastError != nil {
+		return nil, q.lastError
+	}
+	stmt, args := bind(q.rawSQL, q.params)
+	rows, err := q.executor.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &Rows{rows}, nil
+}
+
+// Row runs the query and scans the single resulting row's columns
+// positionally into dest, in the style of sql.Row.Scan.
+func (q *Query) Row(dest ...interface{}) error {
+	if q.lastError != nil {
+		return q.lastError
+	}
+	stmt, args := bind(q.rawSQL, q.params)
+	return q.executor.QueryRow(stmt, args...).Scan(dest...)
+}
+
+// One runs the query and scans the first resulting row into dest, which
+// must be a pointer to a struct (scanned by column name) or a pointer to a
+// scalar value. dest may be nil, in which case the query still runs but no
+// row is scanned. It returns sql.ErrNoRows if there were no results.
+func (q *Query) One(dest interface{}) error {
+	rows, err := q.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if dest == nil {
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return sql.ErrNoRows
+		}
+		return nil
+	}
+	return rows.one(dest)
+}
+
+// All runs the query and scans every resulting row into dest, which must be
+// a pointer to a slice of structs (scanned by column name) or of scalar
+// values. dest may be nil, in which case the query still runs but no rows
+// are scanned.
+func (q *Query) All(dest interface{}) error {
+	rows, err := q.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if dest == nil {
+		for rows.Next() {
+		}
+		return rows.Err()
+	}
+	return rows.all(dest)
+}
+
+// Column runs the query and scans the first column of every resulting row
+// into dest, which must be a pointer to a slice. dest may be nil, in which
+// case the query still runs but no rows are scanned.
+func (q *Query) Column(dest interface{}) error {
+	rows, err := q.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if dest == nil {
+		for rows.Next() {
+		}
+		return rows.Err()
+	}
+	return rows.column(dest)
+}
+
+// Rows wraps *sql.Rows with dbx's struct-scanning helpers.
+type Rows struct {
+	*sql.Rows
+}
+
+func (r *Rows) one(dest interface{}) error {
+	if !r.Next() {
+		if err := r.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	return r.scanInto(dest)
+}
+
+func (r *Rows) all(dest interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return VarTypeError("must be a pointer to a slice")
+	}
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	columns, err := r.Columns()
+	if err != nil {
+		return err
+	}
+	for r.Next() {
+		isPtr := elemType.Kind() == reflect.Ptr
+		baseType := elemType
+		if isPtr {
+			baseType = elemType.Elem()
+		}
+		item := reflect.New(baseType)
+		if baseType.Kind() == reflect.Struct {
+			if err := scanRowIntoStruct(r.Rows, columns, item.Elem()); err != nil {
+				return err
+			}
+		} else if err := r.Scan(item.Interface()); err != nil {
+			return err
+		}
+		if isPtr {
+			slice.Set(reflect.Append(slice, item))
+		} else {
+			slice.Set(reflect.Append(slice, item.Elem()))
+		}
+	}
+	return r.Err()
+}
+
+func (r *Rows) column(dest interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return VarTypeError("must be a pointer to a slice")
+	}
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	for r.Next() {
+		item := reflect.New(elemType)
+		if err := r.Scan(item.Interface()); err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, item.Elem()))
+	}
+	return r.Err()
+}
+
+func (r *Rows) scanInto(dest interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr {
+		return VarTypeError("must be a pointer")
+	}
+	elem := rv.Elem()
+	if elem.Kind() == reflect.Struct {
+		columns, err := r.Columns()
+		if err != nil {
+			return err
+		}
+		return scanRowIntoStruct(r.Rows, columns, elem)
+	}
+	return r.Scan(dest)
+}
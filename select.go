@@ -0,0 +1,680 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// joinClause describes one INNER/LEFT/RIGHT JOIN attached to a SelectQuery.
+type joinClause struct {
+	joinType string
+	table    string
+	on       Expression
+}
+
+// unionClause describes one UNION/UNION ALL leg attached to a SelectQuery.
+type unionClause struct {
+	query *Query
+	all   bool
+}
+
+// SelectQuery builds a SELECT statement. Build a new one with DB.Select or
+// Tx.Select.
+type SelectQuery struct {
+	builder  Builder
+	executor sqlExecutor
+
+	cols         []interface{} // string or Expression
+	distinct     bool
+	selectOption string
+	fromTables   []string
+	joins        []joinClause
+	where        Expression
+	groupBy      []string
+	having       Expression
+	orderBy      []string
+	limit        int64
+	hasLimit     bool
+	offset       int64
+	hasOffset    bool
+	params       Params
+	unions       []unionClause
+
+	execHook func(*SelectQuery, func() error) error
+	oneHook  func(*SelectQuery, interface{}, func(interface{}) error) error
+	allHook  func(*SelectQuery, interface{}, func(interface{}) error) error
+
+	// preloads holds the relations registered via Preload/Preloads.
+	preloads []*preloadSpec
+
+	// ctes holds the common table expressions registered via With/WithRecursive.
+	ctes []cteDef
+
+	// policyContext/bypassPolicies/policyRegistry back the row-level policy
+	// subsystem in policy.go.
+	policyContext  *PolicyContext
+	bypassPolicies bool
+	policyRegistry *policyRegistry
+
+	// lockMode/lockWait/lockOfTables back the locking clauses in locking.go.
+	lockMode     lockMode
+	lockWait     lockWait
+	lockOfTables []string
+
+	lastError error
+}
+
+func newSelectQuery(builder Builder) *SelectQuery {
+	return &SelectQuery{
+		builder:        builder,
+		executor:       builder.executor(),
+		policyRegistry: builder.policyRegistry(),
+		params:         Params{},
+	}
+}
+
+// Select specifies the columns (or Expression, e.g. a window function built
+// with Over) to select, replacing any columns set by a previous call.
+// Omitting every column (an empty call, or never calling Select at all)
+// selects "*".
+func (q *SelectQuery) Select(cols ...interface{}) *SelectQuery {
+	q.cols = cols
+	return q
+}
+
+// AndSelect appends more columns/expressions to the existing selection.
+func (q *SelectQuery) AndSelect(cols ...interface{}) *SelectQuery {
+	q.cols = append(q.cols, cols...)
+	return q
+}
+
+// Distinct toggles SELECT DISTINCT.
+func (q *SelectQuery) Distinct(v bool) *SelectQuery {
+	q.distinct = v
+	return q
+}
+
+// SelectOption sets a raw modifier keyword rendered right after DISTINCT,
+// e.g. "CALC" for MySQL's SQL_CALC_FOUND_ROWS-style extensions.
+func (q *SelectQuery) SelectOption(option string) *SelectQuery {
+	q.selectOption = option
+	return q
+}
+
+// From specifies the FROM tables, replacing any previously set.
+func (q *SelectQuery) From(tables ...string) *SelectQuery {
+	q.fromTables = tables
+	return q
+}
+
+// Where sets the WHERE condition, replacing any previously set.
+func (q *SelectQuery) Where(e Expression) *SelectQuery {
+	q.where = q.checkExpr(e)
+	return q
+}
+
+// AndWhere ANDs e onto the existing WHERE condition.
+func (q *SelectQuery) AndWhere(e Expression) *SelectQuery {
+	e = q.checkExpr(e)
+	if q.where == nil {
+		q.where = e
+	} else {
+		q.where = And(q.where, e)
+	}
+	return q
+}
+
+// OrWhere ORs e onto the existing WHERE condition.
+func (q *SelectQuery) OrWhere(e Expression) *SelectQuery {
+	e = q.checkExpr(e)
+	if q.where == nil {
+		q.where = e
+	} else {
+		q.where = Or(q.where, e)
+	}
+	return q
+}
+
+// InnerJoin adds an INNER JOIN clause. on may be nil to omit the ON clause.
+func (q *SelectQuery) InnerJoin(table string, on Expression) *SelectQuery {
+	q.joins = append(q.joins, joinClause{"INNER JOIN", table, q.checkExpr(on)})
+	return q
+}
+
+// LeftJoin adds a LEFT JOIN clause. on may be nil to omit the ON clause.
+func (q *SelectQuery) LeftJoin(table string, on Expression) *SelectQuery {
+	q.joins = append(q.joins, joinClause{"LEFT JOIN", table, q.checkExpr(on)})
+	return q
+}
+
+// RightJoin adds a RIGHT JOIN clause. on may be nil to omit the ON clause.
+func (q *SelectQuery) RightJoin(table string, on Expression) *SelectQuery {
+	q.joins = append(q.joins, joinClause{"RIGHT JOIN", table, q.checkExpr(on)})
+	return q
+}
+
+// OrderBy specifies the ORDER BY columns, replacing any previously set. Each
+// entry may carry a trailing "ASC"/"DESC" direction, e.g. "age DESC".
+func (q *SelectQuery) OrderBy(cols ...string) *SelectQuery {
+	q.orderBy = cols
+	return q
+}
+
+// AndOrderBy appends more ORDER BY columns to the existing list.
+func (q *SelectQuery) AndOrderBy(cols ...string) *SelectQuery {
+	q.orderBy = append(q.orderBy, cols...)
+	return q
+}
+
+// GroupBy specifies the GROUP BY columns, replacing any previously set.
+func (q *SelectQuery) GroupBy(cols ...string) *SelectQuery {
+	q.groupBy = cols
+	return q
+}
+
+// AndGroupBy appends more GROUP BY columns to the existing list.
+func (q *SelectQuery) AndGroupBy(cols ...string) *SelectQuery {
+	q.groupBy = append(q.groupBy, cols...)
+	return q
+}
+
+// Having sets the HAVING condition, replacing any previously set.
+func (q *SelectQuery) Having(e Expression) *SelectQuery {
+	q.having = q.checkExpr(e)
+	return q
+}
+
+// AndHaving ANDs e onto the existing HAVING condition.
+func (q *SelectQuery) AndHaving(e Expression) *SelectQuery {
+	e = q.checkExpr(e)
+	if q.having == nil {
+		q.having = e
+	} else {
+		q.having = And(q.having, e)
+	}
+	return q
+}
+
+// OrHaving ORs e onto the existing HAVING condition.
+func (q *SelectQuery) OrHaving(e Expression) *SelectQuery {
+	e = q.checkExpr(e)
+	if q.having == nil {
+		q.having = e
+	} else {
+		q.having = Or(q.having, e)
+	}
+	return q
+}
+
+// Limit sets the LIMIT clause.
+func (q *SelectQuery) Limit(limit int64) *SelectQuery {
+	q.limit = limit
+	q.hasLimit = true
+	return q
+}
+
+// Offset sets the OFFSET clause.
+func (q *SelectQuery) Offset(offset int64) *SelectQuery {
+	q.offset = offset
+	q.hasOffset = true
+	return q
+}
+
+// Bind merges params into the query's bound parameters.
+func (q *SelectQuery) Bind(params Params) *SelectQuery {
+	for k, v := range params {
+		q.params[k] = v
+	}
+	return q
+}
+
+// AndBind merges more params into the query's bound parameters.
+func (q *SelectQuery) AndBind(params Params) *SelectQuery {
+	return q.Bind(params)
+}
+
+// Union adds a UNION leg.
+func (q *SelectQuery) Union(query *Query) *SelectQuery {
+	q.unions = append(q.unions, unionClause{query, false})
+	return q
+}
+
+// UnionAll adds a UNION ALL leg.
+func (q *SelectQuery) UnionAll(query *Query) *SelectQuery {
+	q.unions = append(q.unions, unionClause{query, true})
+	return q
+}
+
+// WithExecHook registers a hook that wraps every execution path (Row, Rows,
+// One, All, Column).
+func (q *SelectQuery) WithExecHook(hook func(s *SelectQuery, op func() error) error) *SelectQuery {
+	q.execHook = hook
+	return q
+}
+
+// WithOneHook registers a hook that wraps One, nested inside any exec hook.
+func (q *SelectQuery) WithOneHook(hook func(s *SelectQuery, a interface{}, op func(b interface{}) error) error) *SelectQuery {
+	q.oneHook = hook
+	return q
+}
+
+// WithAllHook registers a hook that wraps All, nested inside any exec hook.
+func (q *SelectQuery) WithAllHook(hook func(s *SelectQuery, a interface{}, op func(b interface{}) error) error) *SelectQuery {
+	q.allHook = hook
+	return q
+}
+
+// copyHooksTo propagates q's exec/one/all hooks onto child, so that queries
+// issued internally (e.g. by Preload) still invoke the caller's hooks.
+func (q *SelectQuery) copyHooksTo(child *SelectQuery) {
+	child.execHook = q.execHook
+	child.oneHook = q.oneHook
+	child.allHook = q.allHook
+}
+
+// checkExpr records the first dialect-compatibility error raised by an
+// expression attached to this query (see expression_ext.go's
+// dialectValidator), surfaced later through Build().Error().
+func (q *SelectQuery) checkExpr(e Expression) Expression {
+	if v, ok := e.(dialectValidator); ok {
+		if err := v.Validate(q.builder); err != nil && q.lastError == nil {
+			q.lastError = err
+		}
+	}
+	return e
+}
+
+// Build renders the query into a *Query ready for binding/execution.
+func (q *SelectQuery) Build() *Query {
+	params := Params{}
+	core := q.buildCore(params)
+
+	lockClause, lockErr := buildLockClause(q.builder, q.lockMode, q.lockWait, q.lockOfTables, len(q.unions) > 0)
+	if lockErr != nil {
+		if q.lastError == nil {
+			q.lastError = lockErr
+		}
+	} else if lockClause != "" {
+		core += " " + lockClause
+	}
+
+	sqlText := core
+	if len(q.unions) > 0 {
+		legs := make([]string, 0, len(q.unions)+1)
+		legs = append(legs, "("+core+")")
+		for _, u := range q.unions {
+			op := "UNION"
+			if u.all {
+				op = "UNION ALL"
+			}
+			legSQL := renameParams(u.query.SQL(), u.query.Params(), params)
+			legs = append(legs, op+" ("+legSQL+")")
+		}
+		sqlText = strings.Join(legs, " ")
+	}
+
+	if cte := buildCTEClause(q.builder, q.ctes, params); cte != "" {
+		sqlText = cte + " " + sqlText
+	}
+
+	query := newQuery(q.executor, sqlText, params)
+	query.lastError = q.lastError
+	return query
+}
+
+func (q *SelectQuery) buildCore(params Params) string {
+	var sb strings.Builder
+	sb.WriteString("SELECT")
+	if q.distinct {
+		sb.WriteString(" DISTINCT")
+	}
+	if q.selectOption != "" {
+		sb.WriteString(" " + q.selectOption)
+	}
+	sb.WriteString(" " + q.buildColumns(params))
+
+	if len(q.fromTables) > 0 {
+		names := make([]string, len(q.fromTables))
+		for i, t := range q.fromTables {
+			names[i] = q.builder.QuoteTableName(t)
+		}
+		sb.WriteString(" FROM " + strings.Join(names, ", "))
+	}
+
+	for _, j := range q.joins {
+		sb.WriteString(" " + j.joinType + " " + q.builder.QuoteTableName(j.table))
+		on := q.combineWithPolicy(j.on, j.table)
+		if on != nil {
+			if s := on.Build(q.builder, params); s != "" {
+				sb.WriteString(" ON " + s)
+			}
+		}
+	}
+
+	where := q.where
+	for _, t := range q.fromTables {
+		where = q.combineWithPolicy(where, t)
+	}
+	if where != nil {
+		if s := where.Build(q.builder, params); s != "" {
+			sb.WriteString(" WHERE " + s)
+		}
+	}
+
+	if len(q.groupBy) > 0 {
+		cols := make([]string, len(q.groupBy))
+		for i, c := range q.groupBy {
+			cols[i] = q.builder.QuoteColumnName(c)
+		}
+		sb.WriteString(" GROUP BY " + strings.Join(cols, ", "))
+	}
+
+	if q.having != nil {
+		if s := q.having.Build(q.builder, params); s != "" {
+			sb.WriteString(" HAVING " + s)
+		}
+	}
+
+	if len(q.orderBy) > 0 {
+		cols := make([]string, len(q.orderBy))
+		for i, c := range q.orderBy {
+			cols[i] = quoteOrderByColumn(q.builder, c)
+		}
+		sb.WriteString(" ORDER BY " + strings.Join(cols, ", "))
+	}
+
+	if q.hasLimit {
+		sb.WriteString(fmt.Sprintf(" LIMIT %d", q.limit))
+	}
+	if q.hasOffset {
+		sb.WriteString(fmt.Sprintf(" OFFSET %d", q.offset))
+	}
+
+	for k, v := range q.params {
+		params[k] = v
+	}
+
+	return sb.String()
+}
+
+func (q *SelectQuery) buildColumns(params Params) string {
+	parts := make([]string, 0, len(q.cols))
+	for _, c := range q.cols {
+		switch v := c.(type) {
+		case string:
+			if v == "" {
+				continue
+			}
+			parts = append(parts, q.builder.QuoteColumnName(v))
+		case Expression:
+			parts = append(parts, v.Build(q.builder, params))
+		}
+	}
+	if len(parts) == 0 {
+		return "*"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Row runs the query and scans the single resulting row's columns
+// positionally into dest.
+func (q *SelectQuery) Row(dest ...interface{}) error {
+	return q.runOp(func() error {
+		return q.Build().Row(dest...)
+	})
+}
+
+// Rows runs the query and returns the resulting *Rows for manual scanning.
+func (q *SelectQuery) Rows() (*Rows, error) {
+	var rows *Rows
+	err := q.runOp(func() error {
+		r, err := q.Build().Rows()
+		rows = r
+		return err
+	})
+	return rows, err
+}
+
+// Column runs the query and scans the first column of every row into dest.
+func (q *SelectQuery) Column(dest interface{}) error {
+	return q.runOp(func() error {
+		return q.Build().Column(dest)
+	})
+}
+
+// One runs the query, scans the first row into dest (which may be nil), and
+// then loads any relations registered with Preload/Preloads.
+func (q *SelectQuery) One(dest interface{}) error {
+	return q.runOp(func() error {
+		if q.oneHook != nil {
+			return q.oneHook(q, dest, q.runOne)
+		}
+		return q.runOne(dest)
+	})
+}
+
+func (q *SelectQuery) runOne(dest interface{}) error {
+	if dest != nil {
+		q.inferFromDest(dest, false)
+	}
+	if err := q.Build().One(dest); err != nil {
+		return err
+	}
+	if dest == nil {
+		return nil
+	}
+	return q.runPreloads(dest)
+}
+
+// All runs the query, scans every row into dest (which may be nil), and
+// then loads any relations registered with Preload/Preloads.
+func (q *SelectQuery) All(dest interface{}) error {
+	return q.runOp(func() error {
+		if q.allHook != nil {
+			return q.allHook(q, dest, q.runAll)
+		}
+		return q.runAll(dest)
+	})
+}
+
+func (q *SelectQuery) runAll(dest interface{}) error {
+	if dest != nil {
+		q.inferFromDest(dest, true)
+	}
+	if err := q.Build().All(dest); err != nil {
+		return err
+	}
+	if dest == nil {
+		return nil
+	}
+	return q.runPreloads(dest)
+}
+
+func (q *SelectQuery) runOp(op func() error) error {
+	if q.execHook != nil {
+		return q.execHook(q, op)
+	}
+	return op()
+}
+
+// inferFromDest sets the FROM table from dest's struct type when From was
+// never called explicitly, so that e.g. db.Select().One(&customer) knows to
+// query the "customer" table.
+func (q *SelectQuery) inferFromDest(dest interface{}, isSlice bool) {
+	if len(q.fromTables) > 0 {
+		return
+	}
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr {
+		return
+	}
+	t := rv.Elem().Type()
+	if isSlice {
+		if t.Kind() != reflect.Slice {
+			return
+		}
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	q.From(tableNameOf(t))
+}
+
+// Model loads the single row whose primary key matches pk into dest, which
+// must be a pointer to a struct with at least one field tagged `db:"pk"`.
+// pk may be a single value, a []interface{}/CompositePK, or a struct, to
+// support composite primary keys (see pk.go).
+func (q *SelectQuery) Model(pk interface{}, dest interface{}) error {
+	_, _, where, err := q.resolvePK(pk, dest)
+	if err != nil {
+		return err
+	}
+	return q.AndWhere(where).One(dest)
+}
+
+// Update writes every exported, non-pk field of data to the row whose
+// primary key matches pk, using the table inferred from data's type (or set
+// via From) and honoring composite primary keys the same way Model does.
+// data must be a pointer to a struct with at least one field tagged
+// `db:"pk"`; every other field is written under its columnName.
+func (q *SelectQuery) Update(pk interface{}, data interface{}) error {
+	t, fields, where, err := q.resolvePK(pk, data)
+	if err != nil {
+		return err
+	}
+
+	isPK := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		isPK[f.Name] = true
+	}
+
+	rv := reflect.ValueOf(data).Elem()
+	params := Params{}
+	var sets []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if isPK[f.Name] {
+			continue
+		}
+		col := columnName(f)
+		name := paramName(params, col)
+		params[name] = rv.Field(i).Interface()
+		sets = append(sets, q.builder.QuoteColumnName(col)+"={:"+name+"}")
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+
+	table := q.fromTables[0]
+	sqlText := "UPDATE " + q.builder.QuoteTableName(table) + " SET " + strings.Join(sets, ", ")
+	if s := q.combineWithPolicy(where, table).Build(q.builder, params); s != "" {
+		sqlText += " WHERE " + s
+	}
+
+	return q.runOp(func() error {
+		_, err := newQuery(q.executor, sqlText, params).Execute()
+		return err
+	})
+}
+
+// Delete removes the row whose primary key matches pk from the table
+// inferred from structType's type (or set via From), honoring composite
+// primary keys the same way Model does. structType need only be a pointer to
+// a (possibly zero-value) struct of the target type; its field values are
+// not otherwise used.
+func (q *SelectQuery) Delete(pk interface{}, structType interface{}) error {
+	_, _, where, err := q.resolvePK(pk, structType)
+	if err != nil {
+		return err
+	}
+
+	table := q.fromTables[0]
+	sqlText := "DELETE FROM " + q.builder.QuoteTableName(table)
+	params := Params{}
+	if s := q.combineWithPolicy(where, table).Build(q.builder, params); s != "" {
+		sqlText += " WHERE " + s
+	}
+
+	return q.runOp(func() error {
+		_, err := newQuery(q.executor, sqlText, params).Execute()
+		return err
+	})
+}
+
+// resolvePK is the shared first half of Model, Update, and Delete: it
+// validates dest is a pointer to a struct carrying at least one `db:"pk"`
+// field, resolves pk against those fields (supporting composite primary
+// keys, see pk.go), and defaults q's FROM table to the struct's table name
+// when it hasn't been set explicitly.
+func (q *SelectQuery) resolvePK(pk interface{}, dest interface{}) (reflect.Type, []reflect.StructField, Expression, error) {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return nil, nil, nil, VarTypeError("must be a pointer to a struct")
+	}
+	t := rv.Elem().Type()
+
+	fields := pkStructFields(t)
+	if len(fields) == 0 {
+		return nil, nil, nil, MissingPKError
+	}
+
+	values, err := pkValues(pk, t, fields)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if len(q.fromTables) == 0 {
+		q.From(tableNameOf(t))
+	}
+	return t, fields, pkWhere(fields, values), nil
+}
+
+// combineWithPolicy ANDs table's registered row-level policy expression
+// (see policy.go) onto expr, returning expr unchanged if no policy applies.
+func (q *SelectQuery) combineWithPolicy(expr Expression, table string) Expression {
+	policy := q.policyExpr(table)
+	if policy == nil {
+		return expr
+	}
+	if expr == nil {
+		return policy
+	}
+	return And(expr, policy)
+}
+
+// renameParams rewrites any {:name} placeholder in sqlText whose name
+// already exists in existing to a fresh, non-colliding name, merges
+// subParams into existing under their (possibly renamed) keys, and returns
+// the possibly-rewritten SQL text. It is shared by CTE and UNION leg
+// composition, both of which bind an independently-built *Query's params
+// into the same outer parameter set.
+func renameParams(sqlText string, subParams Params, existing Params) string {
+	// taken also holds every subParams key, so a fresh name never equals a
+	// key not yet merged (whose ReplaceAll would rewrite it a second time).
+	taken := Params{}
+	for k := range existing {
+		taken[k] = nil
+	}
+	for k := range subParams {
+		taken[k] = nil
+	}
+	for k, v := range subParams {
+		name := k
+		if _, collide := existing[name]; collide {
+			name = paramName(taken, k)
+			taken[name] = nil
+			sqlText = strings.ReplaceAll(sqlText, "{:"+k+"}", "{:"+name+"}")
+		}
+		existing[name] = v
+	}
+	return sqlText
+}
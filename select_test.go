@@ -152,12 +152,99 @@ func TestSelectQuery_Model(t *testing.T) {
 
 		err = db.Select().Model(1, &a)
 		assert.Equal(t, MissingPKError, err)
-		var b struct {
-			ID1 string `db:"pk"`
-			ID2 string `db:"pk"`
-		}
+
+		var b compositeRow
 		err = db.Select().Model(1, &b)
-		assert.Equal(t, CompositePKError, err)
+		assert.Error(t, err, "wrong number of composite key values")
+
+		err = db.Select().Model([]interface{}{"a", "b"}, &b)
+		assert.Nil(t, err)
+
+		err = db.Select().Model(CompositePK{Values: []interface{}{"a", "b"}}, &b)
+		assert.Nil(t, err)
+
+		err = db.Select().Model(struct{ ID1, ID2 string }{"a", "b"}, &b)
+		assert.Nil(t, err)
+	}
+}
+
+func TestSelectQuery_Update(t *testing.T) {
+	db := getPreparedDB()
+	defer db.Close()
+
+	{
+		// Update without specifying FROM
+		err := db.Select().Update(2, &Customer{Email: "updated@example.com"})
+		assert.Nil(t, err)
+
+		var customer CustomerPtr
+		err = db.Select().Model(2, &customer)
+		if assert.Nil(t, err) {
+			assert.Equal(t, "updated@example.com", *customer.Email)
+		}
+	}
+
+	{
+		// composite primary key: every field of compositeRow is part of the
+		// key, so there's nothing left to SET; Update should resolve the
+		// composite key without error and become a no-op.
+		err := db.Select().Update([]interface{}{"a", "b"}, &compositeRow{ID1: "a", ID2: "b"})
+		assert.Nil(t, err)
+	}
+
+	{
+		// errors
+		var i int
+		err := db.Select().Update(1, &i)
+		assert.Equal(t, VarTypeError("must be a pointer to a struct"), err)
+
+		var a struct {
+			Name string
+		}
+		err = db.Select().Update(1, &a)
+		assert.Equal(t, MissingPKError, err)
+	}
+}
+
+func TestSelectQuery_Delete(t *testing.T) {
+	db := getPreparedDB()
+	defer db.Close()
+
+	{
+		// Delete without specifying FROM
+		err := db.Select().Delete(3, &Customer{})
+		assert.Nil(t, err)
+
+		var customers []Customer
+		err = db.Select().All(&customers)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 2, len(customers))
+		}
+	}
+
+	{
+		// composite primary key
+		err := db.Select().Delete([]interface{}{"a", "b"}, &compositeRow{})
+		assert.Nil(t, err)
+
+		var rows []compositeRow
+		err = db.Select().All(&rows)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 0, len(rows))
+		}
+	}
+
+	{
+		// errors
+		var i int
+		err := db.Select().Delete(1, &i)
+		assert.Equal(t, VarTypeError("must be a pointer to a struct"), err)
+
+		var a struct {
+			Name string
+		}
+		err = db.Select().Delete(1, &a)
+		assert.Equal(t, MissingPKError, err)
 	}
 }
 
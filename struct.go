@@ -0,0 +1,122 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+)
+
+// splitTag splits a `db:"..."` tag value on commas.
+func splitTag(tag string) []string {
+	if tag == "" {
+		return []string{""}
+	}
+	return strings.Split(tag, ",")
+}
+
+// columnName returns the db column name for a struct field: the first
+// comma-separated tag segment that isn't the "pk" marker, falling back to
+// the lower-cased field name when the tag carries no explicit name (e.g.
+// `db:"pk"` or `db:""`).
+func columnName(f reflect.StructField) string {
+	for _, part := range splitTag(f.Tag.Get("db")) {
+		if part != "" && part != "pk" && !strings.HasPrefix(part, "rel=") {
+			return part
+		}
+	}
+	return strings.ToLower(f.Name)
+}
+
+// pkStructFields returns, in declaration order, the fields of t tagged
+// `db:"pk"` (or "<name>,pk").
+func pkStructFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		for _, part := range splitTag(f.Tag.Get("db")) {
+			if part == "pk" {
+				fields = append(fields, f)
+				break
+			}
+		}
+	}
+	return fields
+}
+
+// primaryKeyColumn returns the column name of t's first pk field, defaulting
+// to "id" when t declares none (used as the child-side join column for
+// many-to-many preloads).
+func primaryKeyColumn(t reflect.Type) string {
+	if fields := pkStructFields(t); len(fields) > 0 {
+		return columnName(fields[0])
+	}
+	return "id"
+}
+
+// tableNameOf derives the default table name for a struct type: the type
+// name, with a trailing "Ptr" suffix removed (so *Ptr result structs map to
+// the same table as their non-pointer-field counterpart), lower-cased.
+func tableNameOf(t reflect.Type) string {
+	return strings.ToLower(strings.TrimSuffix(t.Name(), "Ptr"))
+}
+
+// singularize strips a trailing "s" from a table name, used to derive the
+// conventional pivot-table column that references a related table's row
+// (e.g. table "tags" -> join column "tag_id").
+func singularize(table string) string {
+	if strings.HasSuffix(table, "s") {
+		return table[:len(table)-1]
+	}
+	return table
+}
+
+// scanRowIntoStruct scans the current row of rows into the struct value
+// dest (addressable, already reflect.Indirect'd), matching result columns
+// to struct fields via columnName. Columns with no matching field are
+// discarded. Pointer fields (e.g. `*string`) are populated with a freshly
+// allocated value.
+func scanRowIntoStruct(rows *sql.Rows, columns []string, dest reflect.Value) error {
+	t := dest.Type()
+	fieldIndex := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		fieldIndex[columnName(t.Field(i))] = i
+	}
+
+	targets := make([]interface{}, len(columns))
+	ptrFields := map[int]reflect.Value{}
+	for i, col := range columns {
+		idx, ok := fieldIndex[col]
+		if !ok {
+			var discard interface{}
+			targets[i] = &discard
+			continue
+		}
+		f := dest.Field(idx)
+		if f.Kind() == reflect.Ptr {
+			ptr := reflect.New(f.Type().Elem())
+			targets[i] = ptr.Interface()
+			ptrFields[i] = ptr
+			continue
+		}
+		targets[i] = f.Addr().Interface()
+	}
+
+	if err := rows.Scan(targets...); err != nil {
+		return err
+	}
+
+	for i, col := range columns {
+		idx, ok := fieldIndex[col]
+		if !ok {
+			continue
+		}
+		if ptr, isPtr := ptrFields[i]; isPtr {
+			dest.Field(idx).Set(ptr)
+		}
+	}
+	return nil
+}
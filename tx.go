@@ -0,0 +1,41 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import "database/sql"
+
+// Tx wraps a *sql.Tx with the same dialect-aware query building as DB.
+// Create one with DB.Begin.
+type Tx struct {
+	*sql.Tx
+	BaseBuilder
+	builder  Builder
+	policies *policyRegistry
+}
+
+func (tx *Tx) executor() sqlExecutor { return tx.Tx }
+
+func (tx *Tx) policyRegistry() *policyRegistry { return tx.policies }
+
+// Select starts a new SelectQuery against this Tx.
+func (tx *Tx) Select(cols ...interface{}) *SelectQuery {
+	return newSelectQuery(tx.builder).Select(cols...)
+}
+
+// NewQuery prepares a raw SQL statement (as used by generated dbxgen code)
+// for binding and execution against this Tx.
+func (tx *Tx) NewQuery(sqlText string) *Query {
+	return newQuery(tx.Tx, sqlText, nil)
+}
+
+// Commit commits the transaction.
+func (tx *Tx) Commit() error {
+	return tx.Tx.Commit()
+}
+
+// Rollback rolls back the transaction.
+func (tx *Tx) Rollback() error {
+	return tx.Tx.Rollback()
+}
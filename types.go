@@ -0,0 +1,21 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbx provides a set of DB-agnostic and easy-to-use query building
+// and scanning methods to facilitate writing SQL statements and processing
+// the corresponding query results.
+package dbx
+
+// Params represents a list of parameter values to be bound to a SQL
+// statement. The map keys are the parameter names while the map values are
+// the corresponding parameter values.
+type Params map[string]interface{}
+
+// Expression is the interface that should be implemented by expressions
+// that can be rendered into SQL. Build should quote identifiers through
+// builder (so the generated SQL matches the active dialect) and register
+// any parameter values it needs into params.
+type Expression interface {
+	Build(builder Builder, params Params) string
+}
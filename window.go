@@ -0,0 +1,86 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import "strings"
+
+// Window describes the OVER(...) clause applied to a window function, built
+// up with PartitionBy, OrderBy, and Frame.
+type Window struct {
+	partitionBy []string
+	orderBy     []string
+	frame       string
+}
+
+// NewWindow creates an empty Window ready to be configured.
+func NewWindow() *Window {
+	return &Window{}
+}
+
+// PartitionBy sets the PARTITION BY column list.
+func (w *Window) PartitionBy(columns ...string) *Window {
+	w.partitionBy = columns
+	return w
+}
+
+// OrderBy sets the ORDER BY column list used within the window.
+func (w *Window) OrderBy(columns ...string) *Window {
+	w.orderBy = columns
+	return w
+}
+
+// Frame sets the raw frame clause, e.g. "ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW".
+func (w *Window) Frame(frame string) *Window {
+	w.frame = frame
+	return w
+}
+
+// Build renders the window's OVER(...) body (without the surrounding
+// "OVER" keyword), quoting identifiers through builder.
+func (w *Window) Build(builder Builder) string {
+	var parts []string
+	if len(w.partitionBy) > 0 {
+		cols := make([]string, len(w.partitionBy))
+		for i, c := range w.partitionBy {
+			cols[i] = builder.QuoteColumnName(c)
+		}
+		parts = append(parts, "PARTITION BY "+strings.Join(cols, ", "))
+	}
+	if len(w.orderBy) > 0 {
+		cols := make([]string, len(w.orderBy))
+		for i, c := range w.orderBy {
+			cols[i] = quoteOrderByColumn(builder, c)
+		}
+		parts = append(parts, "ORDER BY "+strings.Join(cols, ", "))
+	}
+	if w.frame != "" {
+		parts = append(parts, w.frame)
+	}
+	return strings.Join(parts, " ")
+}
+
+// windowExpression wraps a function expression (e.g. "ROW_NUMBER()") with an
+// OVER(...) clause produced by a Window.
+type windowExpression struct {
+	fn     Expression
+	window *Window
+}
+
+// Over combines fn (typically a raw Expression such as NewExp("ROW_NUMBER()")
+// or NewExp("SUM(amount)")) with w into a single selectable expression of
+// the form "fn OVER (...)".
+func Over(fn Expression, w *Window) Expression {
+	return &windowExpression{fn: fn, window: w}
+}
+
+// Build implements Expression.
+func (e *windowExpression) Build(builder Builder, params Params) string {
+	body := e.fn.Build(builder, params)
+	over := e.window.Build(builder)
+	if over == "" {
+		return body + " OVER ()"
+	}
+	return body + " OVER (" + over + ")"
+}
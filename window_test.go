@@ -0,0 +1,31 @@
+// Copyright 2016 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindow_Build(t *testing.T) {
+	db := getDB()
+
+	w := NewWindow().PartitionBy("dept").OrderBy("salary DESC")
+	q := db.Select("id").
+		AndSelect(Over(NewExp("ROW_NUMBER()"), w)).
+		From("employees").
+		Build()
+
+	assert.Equal(t, "SELECT `id`, ROW_NUMBER() OVER (PARTITION BY `dept` ORDER BY `salary` DESC) FROM `employees`", q.SQL())
+}
+
+func TestWindow_Frame(t *testing.T) {
+	db := getDB()
+
+	w := NewWindow().OrderBy("id").Frame("ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW")
+	expr := Over(NewExp("SUM(amount)"), w)
+	assert.Equal(t, "SUM(amount) OVER (ORDER BY `id` ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW)", expr.Build(db.builder, Params{}))
+}